@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/bundle"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+)
+
+func newCaptureCommand(opts *options) *cobra.Command {
+	var (
+		output    string
+		anonymize bool
+		salt      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "capture",
+		Short: "Save the raw output of every command the exporter runs to a support bundle",
+		Long: `Run every storcli or MegaCLI command the exporter uses and save the raw output,
+the tool version and a manifest to a gzipped tarball. Bundles can be attached to vendor
+tickets, used as regression fixtures and compared with "megaraid-exporter diff".
+
+--anonymize replaces serial numbers, WWNs, SAS addresses, MegaCLI inquiry data and the
+host name with keyed hashes, and writes no bundle when an output cannot be searched for
+them. Pass the same --salt to several captures to keep the hashes comparable.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCapture(opts, output, anonymize, salt)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "out", "o", "", "Bundle file to write, e.g. bundle.tar.gz")
+	cmd.Flags().BoolVar(&anonymize, "anonymize", false, "Hash serial numbers, WWNs and host names")
+	cmd.Flags().StringVar(&salt, "salt", "", "Key for --anonymize hashes (default: random per bundle)")
+	cmd.MarkFlagRequired("out")
+
+	return cmd
+}
+
+func runCapture(opts *options, output string, anonymize bool, salt string) error {
+	cfg, err := setup(opts)
+	if err != nil {
+		return err
+	}
+
+	b, err := newBackend(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Run the backend once more through a recorder with the same settings
+	recorder := &runner.Recorder{Runner: runner.Local{Timeout: viper.GetDuration("command_timeout")}}
+	recorded, err := backend.New(b.Name(), recorder, b.Path(), b.Path(), cfg.MegaRAID.Controllers)
+	if err != nil {
+		return err
+	}
+
+	manifest := bundle.Manifest{
+		CreatedAt:       time.Now().UTC(),
+		ExporterVersion: version,
+		Backend:         recorded.Name(),
+		ToolPath:        recorded.Path(),
+		Controllers:     cfg.MegaRAID.Controllers,
+	}
+	manifest.Hostname, _ = os.Hostname()
+	if manifest.ToolVersion, err = recorded.Version(); err != nil {
+		log.Warnf("Failed to read %s version: %v", recorded.Name(), err)
+	}
+	snapshot := recorded.Collect(backend.Sections)
+	for section, msg := range snapshot.Errors {
+		log.Warnf("Collecting %s failed, its output is still saved: %s", section, msg)
+	}
+
+	bnd := bundle.New(manifest, recorder.Records())
+	if anonymize {
+		if err := bnd.Anonymize(bundle.NewAnonymizer(salt)); err != nil {
+			return fmt.Errorf("failed to anonymize, no bundle written: %v", err)
+		}
+	}
+	if err := bnd.WriteFile(output); err != nil {
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+
+	log.Infof("Wrote %d command outputs to %s", len(bnd.Manifest.Commands), output)
+	return nil
+}
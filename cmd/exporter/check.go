@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+// Plugin exit codes of the Nagios plugin API
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// checkLimitFlags maps the threshold flags of the check command to the
+// values they set
+var checkLimitFlags = []struct {
+	flag, name, usage string
+}{
+	{"controller-temp", threshold.ControllerTemperature, "Controller temperature"},
+	{"roc-temp", threshold.ROCTemperature, "ROC temperature"},
+	{"drive-temp", threshold.DriveTemperature, "Drive temperature"},
+	{"bbu-temp", threshold.BBUTemperature, "BBU or CacheVault temperature"},
+	{"media-errors", threshold.MediaErrors, "Drive media error count"},
+	{"other-errors", threshold.OtherErrors, "Drive other error count"},
+	{"predictive-failures", threshold.PredictiveFailures, "Drive predictive failure count"},
+}
+
+func newCheckCommand(opts *options) *cobra.Command {
+	sections := append([]string(nil), backend.Sections...)
+	limits := make(map[string]*string)
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Run as a Nagios or Icinga plugin",
+		Long: `Collect once, print one status line with perfdata for temperatures and error
+counters followed by one line per problem, and exit 0 (OK), 1 (WARNING),
+2 (CRITICAL) or 3 (UNKNOWN). A section that fails to collect makes the result
+UNKNOWN unless something is critical.
+
+Threshold flags take WARNING:CRITICAL, either side may be left empty to disable
+it, and replace the configured limits and rules of that value.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			os.Exit(runCheck(opts, os.Stdout, sections, limits))
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&sections, "collectors", sections, "What to check: "+strings.Join(backend.Sections, ", "))
+	for _, f := range checkLimitFlags {
+		limits[f.name] = cmd.Flags().String(f.flag, "", f.usage+" levels as WARNING:CRITICAL")
+	}
+
+	return cmd
+}
+
+func runCheck(opts *options, out io.Writer, sections []string, limits map[string]*string) int {
+	unknown := func(format string, args ...interface{}) int {
+		fmt.Fprintf(out, "MEGARAID UNKNOWN - %s\n", fmt.Sprintf(format, args...))
+		return checkUnknown
+	}
+
+	cfg, err := setup(opts)
+	if err != nil {
+		return unknown("%v", err)
+	}
+	for _, section := range sections {
+		if !contains(backend.Sections, section) {
+			return unknown("unknown collector: %s", section)
+		}
+	}
+
+	// Limits from flags replace the configured ones, rules included
+	thresholdsCfg := threshold.Config{Limits: make(map[string]threshold.Limit)}
+	for name, limit := range cfg.Thresholds.Limits {
+		thresholdsCfg.Limits[name] = limit
+	}
+	overridden := make(map[string]bool)
+	for name, value := range limits {
+		if *value == "" {
+			continue
+		}
+		limit, err := parseLimit(*value)
+		if err != nil {
+			return unknown("invalid %s levels: %v", name, err)
+		}
+		thresholdsCfg.Limits[name] = limit
+		overridden[name] = true
+	}
+	for _, rule := range cfg.Thresholds.Rules {
+		kept := rule
+		kept.Limits = make(map[string]threshold.Limit)
+		for name, limit := range rule.Limits {
+			if !overridden[name] {
+				kept.Limits[name] = limit
+			}
+		}
+		thresholdsCfg.Rules = append(thresholdsCfg.Rules, kept)
+	}
+	thresholds, err := threshold.New(thresholdsCfg)
+	if err != nil {
+		return unknown("invalid thresholds: %v", err)
+	}
+
+	b, err := newBackend(cfg)
+	if err != nil {
+		return unknown("%v", err)
+	}
+	return report(out, b.Collect(sections), thresholds)
+}
+
+// report prints the plugin output for a snapshot and returns the exit code
+func report(out io.Writer, snapshot *diskutil.Snapshot, thresholds *threshold.Evaluator) int {
+	problems := thresholds.Problems(snapshot)
+	code := checkOK
+	counts := make(map[diskutil.Severity]int)
+	for _, problem := range problems {
+		counts[problem.Severity]++
+	}
+	switch {
+	case counts[diskutil.SeverityCritical] > 0:
+		code = checkCritical
+	case len(snapshot.Errors) > 0:
+		code = checkUnknown
+	case counts[diskutil.SeverityWarning] > 0:
+		code = checkWarning
+	}
+
+	summary := inventory(snapshot)
+	if len(problems) > 0 {
+		summary = fmt.Sprintf("%d critical, %d warning: %s: %s",
+			counts[diskutil.SeverityCritical], counts[diskutil.SeverityWarning],
+			problems[0].Component, problems[0].Message)
+	}
+	line := fmt.Sprintf("MEGARAID %s - %s", checkStates[code], summary)
+	if perf := perfdata(snapshot, thresholds); perf != "" {
+		line += " | " + perf
+	}
+	fmt.Fprintln(out, line)
+	for _, problem := range problems {
+		fmt.Fprintf(out, "%s %s: %s\n", strings.ToUpper(problem.Severity.String()), problem.Component, problem.Message)
+	}
+	return code
+}
+
+// parseLimit parses WARNING:CRITICAL, where a missing level is disabled. A
+// single number is the warning level.
+func parseLimit(s string) (threshold.Limit, error) {
+	var limit threshold.Limit
+	warning, critical, _ := strings.Cut(s, ":")
+	for _, level := range []struct {
+		text  string
+		value *float64
+	}{{warning, &limit.Warning}, {critical, &limit.Critical}} {
+		if level.text == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(level.text, 64)
+		if err != nil || v < 0 {
+			return limit, fmt.Errorf("expected WARNING:CRITICAL, got %q", s)
+		}
+		*level.value = v
+	}
+	return limit, nil
+}
+
+// inventory summarizes what was checked when there are no problems
+func inventory(s *diskutil.Snapshot) string {
+	var parts []string
+	for _, count := range []struct {
+		n              int
+		single, plural string
+	}{
+		{len(s.Controllers), "controller", "controllers"},
+		{len(s.VirtualDrives), "virtual drive", "virtual drives"},
+		{len(s.PhysicalDrives), "physical drive", "physical drives"},
+		{len(s.Batteries), "battery", "batteries"},
+	} {
+		switch {
+		case count.n == 1:
+			parts = append(parts, "1 "+count.single)
+		case count.n > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.plural))
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing to check"
+	}
+	return strings.Join(parts, ", ") + " healthy"
+}
+
+// perfdata formats temperatures and error counters with their levels as
+// 'label'=value[UOM];warn;crit
+func perfdata(s *diskutil.Snapshot, t *threshold.Evaluator) string {
+	var items []string
+	add := func(label, uom, name, component, model string, value float64) {
+		limit := t.Limit(name, component, model)
+		items = append(items, fmt.Sprintf("'%s'=%s%s;%s;%s", label, formatLevel(value, true), uom,
+			formatLevel(limit.Warning, false), formatLevel(limit.Critical, false)))
+	}
+
+	for _, ctrl := range s.Controllers {
+		component := fmt.Sprintf("controller %d", ctrl.AdapterIndex)
+		if ctrl.ROCTemperature > 0 {
+			add(fmt.Sprintf("controller_%d_roc_temp", ctrl.AdapterIndex), "", threshold.ROCTemperature,
+				component, ctrl.ProductName, float64(ctrl.ROCTemperature))
+		}
+		if ctrl.ControllerTemperature > 0 {
+			add(fmt.Sprintf("controller_%d_temp", ctrl.AdapterIndex), "", threshold.ControllerTemperature,
+				component, ctrl.ProductName, float64(ctrl.ControllerTemperature))
+		}
+	}
+	for _, pd := range s.PhysicalDrives {
+		component := fmt.Sprintf("pd %d/%s", pd.AdapterIndex, pd.Slot())
+		prefix := fmt.Sprintf("pd_%d_%s", pd.AdapterIndex, pd.Slot())
+		if temp := diskutil.ParseTemperature(pd.DriveTemperature); temp > 0 {
+			add(prefix+"_temp", "", threshold.DriveTemperature, component, pd.Model, temp)
+		}
+		add(prefix+"_media_errors", "c", threshold.MediaErrors, component, pd.Model, float64(pd.MediaErrorCount))
+		add(prefix+"_other_errors", "c", threshold.OtherErrors, component, pd.Model, float64(pd.OtherErrorCount))
+		add(prefix+"_predictive_failures", "c", threshold.PredictiveFailures, component, pd.Model, float64(pd.PredictiveFailureCount))
+	}
+	for _, bbu := range s.Batteries {
+		if bbu.Temperature > 0 {
+			add(fmt.Sprintf("%s_%d_temp", bbu.Kind(), bbu.AdapterIndex), "", threshold.BBUTemperature,
+				fmt.Sprintf("%s %d", bbu.Kind(), bbu.AdapterIndex), bbu.BatteryType, float64(bbu.Temperature))
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+// formatLevel formats a perfdata value; a disabled level (0) is left empty
+func formatLevel(v float64, always bool) string {
+	if v == 0 && !always {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
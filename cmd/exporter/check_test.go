@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+func TestReport(t *testing.T) {
+	thresholds, err := threshold.New(threshold.Config{Limits: map[string]threshold.Limit{
+		threshold.DriveTemperature: {Warning: 50, Critical: 60},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthy := func() *diskutil.Snapshot {
+		return &diskutil.Snapshot{
+			Controllers:    []*diskutil.ControllerStat{{ControllerStatus: "Optimal", ROCTemperature: 55}},
+			VirtualDrives:  []*diskutil.VirtualDriveStat{{State: "Optl"}},
+			PhysicalDrives: []*diskutil.PhysicalDriveStat{{EnclosureDeviceId: 32, FirmwareState: "Onln", DriveTemperature: "35C"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(s *diskutil.Snapshot)
+		code   int
+		first  string
+		lines  []string
+	}{
+		{
+			name:  "healthy",
+			code:  checkOK,
+			first: "MEGARAID OK - 1 controller, 1 virtual drive, 1 physical drive healthy | ",
+		},
+		{
+			name:   "empty",
+			change: func(s *diskutil.Snapshot) { *s = diskutil.Snapshot{} },
+			code:   checkOK,
+			first:  "MEGARAID OK - nothing to check\n",
+		},
+		{
+			name:   "warm drive",
+			change: func(s *diskutil.Snapshot) { s.PhysicalDrives[0].DriveTemperature = "52C" },
+			code:   checkWarning,
+			first:  "MEGARAID WARNING - 0 critical, 1 warning: pd 0/32:0: drive_temperature is 52, warning level is 50",
+			lines:  []string{"WARNING pd 0/32:0: drive_temperature is 52, warning level is 50"},
+		},
+		{
+			name:   "degraded virtual drive",
+			change: func(s *diskutil.Snapshot) { s.VirtualDrives[0].State = "Dgrd" },
+			code:   checkCritical,
+			first:  "MEGARAID CRITICAL - 1 critical, 0 warning: vd 0/0: state is degraded",
+			lines:  []string{"CRITICAL vd 0/0: state is degraded"},
+		},
+		{
+			name:   "collection failed",
+			change: func(s *diskutil.Snapshot) { s.SetError(backend.SectionBBU, errors.New("no battery")) },
+			code:   checkUnknown,
+			first:  "MEGARAID UNKNOWN - 0 critical, 1 warning: bbu: collection failed: no battery",
+		},
+		{
+			name: "critical wins over a failed collection",
+			change: func(s *diskutil.Snapshot) {
+				s.SetError(backend.SectionBBU, errors.New("no battery"))
+				s.PhysicalDrives[0].FirmwareState = "Offln"
+			},
+			code:  checkCritical,
+			first: "MEGARAID CRITICAL - 1 critical, 1 warning: pd 0/32:0: state is offline",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := healthy()
+			if tt.change != nil {
+				tt.change(snapshot)
+			}
+			var out bytes.Buffer
+			if code := report(&out, snapshot, thresholds); code != tt.code {
+				t.Errorf("got exit code %d, want %d", code, tt.code)
+			}
+			if !strings.HasPrefix(out.String(), tt.first) {
+				t.Errorf("output does not start with %q:\n%s", tt.first, out.String())
+			}
+			for _, line := range tt.lines {
+				if !strings.Contains(out.String(), "\n"+line+"\n") {
+					t.Errorf("output does not contain the line %q:\n%s", line, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestReportPerfdata(t *testing.T) {
+	thresholds, err := threshold.New(threshold.Config{Limits: map[string]threshold.Limit{
+		threshold.ROCTemperature: {Warning: 90, Critical: 100},
+		threshold.MediaErrors:    {Critical: 10},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &diskutil.Snapshot{
+		Controllers:    []*diskutil.ControllerStat{{ControllerStatus: "Optimal", ROCTemperature: 55}},
+		PhysicalDrives: []*diskutil.PhysicalDriveStat{{EnclosureDeviceId: 32, SlotNumber: 1, FirmwareState: "Onln"}},
+	}
+
+	var out bytes.Buffer
+	report(&out, snapshot, thresholds)
+	for _, want := range []string{
+		"'controller_0_roc_temp'=55;90;100",
+		"'pd_0_32:1_media_errors'=0c;;10",
+		"'pd_0_32:1_other_errors'=0c;;",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("perfdata does not contain %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "pd_0_32:1_temp") {
+		t.Errorf("unreported drive temperature in perfdata:\n%s", out.String())
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  threshold.Limit
+		err   bool
+	}{
+		{"50:60", threshold.Limit{Warning: 50, Critical: 60}, false},
+		{"50", threshold.Limit{Warning: 50}, false},
+		{"50:", threshold.Limit{Warning: 50}, false},
+		{":60", threshold.Limit{Critical: 60}, false},
+		{"0.5:1.5", threshold.Limit{Warning: 0.5, Critical: 1.5}, false},
+		{"hot:60", threshold.Limit{}, true},
+		{"-1:60", threshold.Limit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLimit(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: got error %v, want error %v", tt.value, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/yourusername/megaraid-exporter/pkg/bundle"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+func newDiffCommand() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <before> <after>",
+		Short: "Compare two support bundles or JSON snapshots",
+		Long: `Report what changed between two captures ("megaraid-exporter capture") or JSON
+snapshots ("megaraid-exporter status --json"): drives added, removed or replaced in a
+slot, drive and virtual drive state changes, new media errors, cache policy changes and
+firmware changes.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(args[0], args[1], jsonOutput)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the changes as JSON")
+
+	return cmd
+}
+
+func runDiff(beforePath, afterPath string, jsonOutput bool) error {
+	before, err := loadSnapshot(beforePath)
+	if err != nil {
+		return err
+	}
+	after, err := loadSnapshot(afterPath)
+	if err != nil {
+		return err
+	}
+
+	changes := diskutil.Diff(before, after)
+	if jsonOutput {
+		if changes == nil {
+			changes = []diskutil.Change{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printChanges(os.Stdout, changes, isTerminal(os.Stdout))
+	return nil
+}
+
+// loadSnapshot reads a support bundle, recognised by its gzip header, or a
+// JSON snapshot
+func loadSnapshot(path string) (*diskutil.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		b, err := bundle.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return b.Snapshot()
+	}
+
+	var snapshot diskutil.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s is neither a support bundle nor a JSON snapshot: %v", path, err)
+	}
+	return &snapshot, nil
+}
+
+func printChanges(out io.Writer, changes []diskutil.Change, colour bool) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes")
+		return
+	}
+
+	p := painter(colour)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			p.paint(change.Severity, strings.ToUpper(change.Severity.String())), change.Component, change.Message)
+	}
+	w.Flush()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+// checkResult is one line of the doctor report
+type checkResult struct {
+	name   string
+	passed bool
+	detail string
+	hint   string // how to fix a failed check
+}
+
+func newDoctorCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the installation and print a report with remediation hints",
+		Long: `Check that the storcli or MegaCLI binary can be found and executed, that the
+exporter runs as root, that the megaraid_sas kernel module is loaded and that the tool
+returns output the exporter can parse. Exits non-zero when a check fails.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(opts)
+		},
+	}
+}
+
+func runDoctor(opts *options) error {
+	cfg, err := setup(opts)
+	if err != nil {
+		return err
+	}
+
+	var results []checkResult
+	b, err := newBackend(cfg)
+	if err != nil {
+		results = append(results, checkResult{
+			name:   "backend",
+			detail: err.Error(),
+			hint:   "set megaraid.backend to storcli, megacli or auto and megaraid.megacli_path to the MegaCli64 binary",
+		})
+	} else {
+		results = append(results, checkDiscovery(b))
+		results = append(results, checkTool(b))
+	}
+	results = append(results, checkRoot())
+	results = append(results, checkKernelModule())
+	if b != nil {
+		results = append(results, checkInvocation(b))
+	}
+
+	failed := printReport(os.Stdout, results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// checkDiscovery reports which binaries were considered and which backend was picked
+func checkDiscovery(b backend.Backend) checkResult {
+	var found []string
+	for _, path := range append([]string{viper.GetString("storcli_path"), viper.GetString("megacli_path")}, config.DefaultMegaCLIPaths...) {
+		if config.IsValidMegaCLI(path) && !contains(found, path) {
+			found = append(found, path)
+		}
+	}
+	if discovered := config.DiscoverMegaCLI(); discovered != "" && !contains(found, discovered) {
+		found = append(found, discovered)
+	}
+
+	if len(found) == 0 {
+		return checkResult{
+			name:   "discovery",
+			detail: "no storcli or MegaCLI binary found",
+			hint: fmt.Sprintf("install storcli from Broadcom, or set --storcli-path / --megacli-path; MegaCLI is looked for in %s",
+				strings.Join(config.DefaultMegaCLIPaths, ", ")),
+		}
+	}
+	return checkResult{
+		name:   "discovery",
+		passed: true,
+		detail: fmt.Sprintf("using %s backend, found %s", b.Name(), strings.Join(found, ", ")),
+	}
+}
+
+// checkTool checks that the selected binary exists and has the executable bit set
+func checkTool(b backend.Backend) checkResult {
+	result := checkResult{name: "executable"}
+	if err := verifyMegaCLI(b.Path()); err != nil {
+		result.detail = err.Error()
+		if os.IsNotExist(err) {
+			result.hint = fmt.Sprintf("install %s or point --%s-path at it", b.Name(), b.Name())
+		} else {
+			result.hint = fmt.Sprintf("chmod +x %s", b.Path())
+		}
+		return result
+	}
+	result.passed = true
+	result.detail = b.Path()
+	return result
+}
+
+func checkRoot() checkResult {
+	if uid := os.Geteuid(); uid != 0 {
+		return checkResult{
+			name:   "root",
+			detail: fmt.Sprintf("running as uid %d", uid),
+			hint:   "storcli and MegaCLI need root to talk to the controller; run the exporter as root or through sudo",
+		}
+	}
+	return checkResult{name: "root", passed: true, detail: "running as root"}
+}
+
+// checkKernelModule looks for megaraid_sas in sysfs, which also covers a
+// driver built into the kernel, and falls back to /proc/modules
+func checkKernelModule() checkResult {
+	result := checkResult{name: "kernel module"}
+	if _, err := os.Stat("/sys/module/megaraid_sas"); err == nil {
+		result.passed = true
+		result.detail = "megaraid_sas loaded"
+		return result
+	}
+	if data, err := os.ReadFile("/proc/modules"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "megaraid_sas ") {
+				result.passed = true
+				result.detail = "megaraid_sas loaded"
+				return result
+			}
+		}
+	}
+	result.detail = "megaraid_sas is not loaded"
+	result.hint = "modprobe megaraid_sas, and check that lspci lists a MegaRAID controller"
+	return result
+}
+
+// checkInvocation runs the tool once and checks that its output parses
+func checkInvocation(b backend.Backend) checkResult {
+	result := checkResult{name: "test invocation"}
+	snapshot := b.Collect([]string{backend.SectionController})
+	if msg, failed := snapshot.Errors[backend.SectionController]; failed {
+		result.detail = msg
+		result.hint = fmt.Sprintf("run %s by hand to see its output; older firmware may need the other backend (--backend)", b.Path())
+		return result
+	}
+	if len(snapshot.Controllers) == 0 {
+		result.detail = fmt.Sprintf("%s returned no controllers", b.Name())
+		result.hint = "check megaraid.controllers in the config and that the controller is visible to the tool"
+		return result
+	}
+	result.passed = true
+	result.detail = fmt.Sprintf("%s output parsed, %d controller(s)", b.Name(), len(snapshot.Controllers))
+	return result
+}
+
+// printReport prints the results and returns the number of failed checks
+func printReport(out io.Writer, results []checkResult) int {
+	p := painter(isTerminal(os.Stdout))
+	failed := 0
+	for _, r := range results {
+		if r.passed {
+			fmt.Fprintf(out, "[%s] %s: %s\n", p.paint(diskutil.SeverityOK, "PASS"), r.name, r.detail)
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "[%s] %s: %s\n", p.paint(diskutil.SeverityCritical, "FAIL"), r.name, r.detail)
+		if r.hint != "" {
+			fmt.Fprintf(out, "       hint: %s\n", r.hint)
+		}
+	}
+	return failed
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
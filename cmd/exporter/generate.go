@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yourusername/megaraid-exporter/pkg/collector"
+	"github.com/yourusername/megaraid-exporter/pkg/generate"
+)
+
+func newGenerateCommand(opts *options) *cobra.Command {
+	genOpts := generate.Options{}
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Print Prometheus alerting rules or a Grafana dashboard for this build",
+		Long: `Print Prometheus alerting rules ("generate rules") or a Grafana dashboard
+("generate dashboard") that use the metric names this build exports, including the
+configured metrics.namespace. Regenerate them after upgrading the exporter.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&outPath, "out", "", "Write to this file instead of stdout")
+	cmd.PersistentFlags().StringVar(&genOpts.Job, "job", "megaraid", "Prometheus job name that scrapes the exporter")
+	cmd.PersistentFlags().IntVar(&genOpts.DriveTemp, "drive-temp", 55, "Drive temperature (Celsius) to alert on")
+	cmd.PersistentFlags().IntVar(&genOpts.ControllerTemp, "controller-temp", 90, "Controller temperature (Celsius) to alert on")
+	cmd.PersistentFlags().IntVar(&genOpts.BBUTemp, "bbu-temp", 50, "BBU or CacheVault temperature (Celsius) to alert on")
+
+	run := func(render func(generate.Options) ([]byte, error)) error {
+		cfg, err := setup(opts)
+		if err != nil {
+			return err
+		}
+		genOpts.Namespace = cfg.Metrics.Namespace
+		// The backend is not used to describe metrics
+		genOpts.Exported = collector.NewMegaRAIDCollector(nil, cfg).MetricNames()
+
+		data, err := render(genOpts)
+		if err != nil {
+			return err
+		}
+		if outPath == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(outPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", outPath, err)
+		}
+		log.Infof("Wrote %s", outPath)
+		return nil
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rules",
+		Short: "Print Prometheus alerting rules",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(generate.Rules)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "dashboard",
+		Short: "Print a Grafana dashboard",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(generate.Dashboard)
+		},
+	})
+
+	return cmd
+}
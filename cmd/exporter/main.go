@@ -9,11 +9,28 @@ import (
 	"syscall"
 	"time"
 
+	kitlog "github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/api"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/collector"
+	"github.com/yourusername/megaraid-exporter/pkg/history"
+	"github.com/yourusername/megaraid-exporter/pkg/kmsg"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+	"github.com/yourusername/megaraid-exporter/pkg/notify"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/risk"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+	"github.com/yourusername/megaraid-exporter/pkg/ui"
 )
 
 var (
@@ -27,29 +44,57 @@ func main() {
 	}
 }
 
+// options holds the flags shared by the exporter and its subcommands
+type options struct {
+	configFile  string
+	port        int
+	megacliPath string
+	storcliPath string
+	backend     string
+	logLevel    string
+	timeout     int
+	kmsgPath    string
+	webConfig   string
+	stateDir    string
+}
+
 func newRootCommand() *cobra.Command {
-	var (
-		configFile   string
-		port         int
-		megacliPath  string
-		logLevel     string
-		timeout      int
-	)
+	opts := &options{}
 
 	cmd := &cobra.Command{
 		Use:   "megaraid-exporter",
 		Short: "Prometheus exporter for MegaRAID controllers",
 		Long:  "A Prometheus exporter that collects metrics from MegaRAID controllers using MegaCLI64",
+		// Errors are logged by main, usage is only shown for flag errors
+		SilenceUsage:  true,
+		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return run(configFile, port, megacliPath, logLevel, timeout)
+			return run(opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Configuration file path")
-	cmd.Flags().IntVarP(&port, "port", "p", 9272, "HTTP port to listen on")
-	cmd.Flags().StringVar(&megacliPath, "megacli-path", "/usr/sbin/megacli64", "Path to megacli64 binary")
-	cmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
-	cmd.Flags().IntVar(&timeout, "timeout", 30, "Command timeout in seconds")
+	cmd.PersistentFlags().StringVarP(&opts.configFile, "config", "c", "", "Configuration file path")
+	cmd.Flags().IntVarP(&opts.port, "port", "p", 9272, "HTTP port to listen on")
+	cmd.PersistentFlags().StringVar(&opts.megacliPath, "megacli-path", "/usr/sbin/megacli64", "Path to megacli64 binary")
+	cmd.PersistentFlags().StringVar(&opts.storcliPath, "storcli-path", "/usr/sbin/storcli64", "Path to storcli64 binary")
+	cmd.PersistentFlags().StringVar(&opts.backend, "backend", "", "Collection backend: storcli, megacli or auto (default from config, auto)")
+	cmd.PersistentFlags().StringVar(&opts.logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	cmd.PersistentFlags().IntVar(&opts.timeout, "timeout", 30, "Command timeout in seconds")
+	cmd.Flags().StringVar(&opts.webConfig, "web.config.file", "", "Path to exporter-toolkit web config file that can enable TLS or authentication")
+	cmd.Flags().StringVar(&opts.kmsgPath, "kmsg-path", kmsg.DefaultPath, "Kernel log to follow for megaraid_sas events (empty to disable)")
+	cmd.Flags().StringVar(&opts.stateDir, "state-dir", "", "Directory to keep counters and history in across restarts (default from config, in memory only)")
+
+	// Flags given on the command line win over the config file, which wins
+	// over the flag defaults
+	for key, flag := range map[string]*pflag.Flag{
+		"port":            cmd.Flags().Lookup("port"),
+		"megacli_path":    cmd.PersistentFlags().Lookup("megacli-path"),
+		"storcli_path":    cmd.PersistentFlags().Lookup("storcli-path"),
+		"kmsg_path":       cmd.Flags().Lookup("kmsg-path"),
+		"web_config_file": cmd.Flags().Lookup("web.config.file"),
+	} {
+		viper.BindPFlag(key, flag)
+	}
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -58,82 +103,195 @@ func newRootCommand() *cobra.Command {
 			fmt.Printf("megaraid-exporter version %s\n", version)
 		},
 	})
+	cmd.AddCommand(newTextfileCommand(opts))
+	cmd.AddCommand(newStatusCommand(opts))
+	cmd.AddCommand(newCheckCommand(opts))
+	cmd.AddCommand(newDoctorCommand(opts))
+	cmd.AddCommand(newCaptureCommand(opts))
+	cmd.AddCommand(newDiffCommand())
+	cmd.AddCommand(newGenerateCommand(opts))
 
 	return cmd
 }
 
-func run(configFile string, port int, megacliPath, logLevel string, timeout int) error {
-	// Setup logging
-	level, err := logrus.ParseLevel(logLevel)
+// setup configures logging and loads the configuration. Flat keys (port,
+// megacli_path, ...) are read through viper, structured sections through
+// config.LoadFile; both come from the same file.
+func setup(opts *options) (*config.Config, error) {
+	level, err := logrus.ParseLevel(opts.logLevel)
 	if err != nil {
-		return fmt.Errorf("invalid log level: %v", err)
+		return nil, fmt.Errorf("invalid log level: %v", err)
 	}
 	log.SetLevel(level)
 	log.SetFormatter(&logrus.JSONFormatter{})
 
-	// Load configuration
-	if configFile != "" {
-		viper.SetConfigFile(configFile)
+	cfg := config.NewConfig()
+	if opts.configFile != "" {
+		viper.SetConfigFile(opts.configFile)
 		if err := viper.ReadInConfig(); err != nil {
 			log.Warnf("Failed to read config file: %v", err)
+		} else if cfg, err = config.LoadFile(opts.configFile); err != nil {
+			return nil, err
 		}
 	}
 
-	// Override with command line flags
-	viper.SetDefault("port", port)
-	viper.SetDefault("megacli_path", megacliPath)
-	viper.SetDefault("command_timeout", fmt.Sprintf("%ds", timeout))
+	// The structured config section replaces the flag defaults, but not
+	// flags given on the command line (see newRootCommand)
+	viper.SetDefault("command_timeout", fmt.Sprintf("%ds", opts.timeout))
+	if cfg.MegaRAID.StorCLIPath != "" {
+		viper.SetDefault("storcli_path", cfg.MegaRAID.StorCLIPath)
+	}
+	if cfg.MegaCLIPath != "" {
+		viper.SetDefault("megacli_path", cfg.MegaCLIPath)
+	}
+	if opts.backend != "" {
+		cfg.MegaRAID.Backend = opts.backend
+	}
+	if opts.stateDir != "" {
+		cfg.State.Directory = opts.stateDir
+	}
+
+	return cfg, nil
+}
+
+// newBackend returns the configured storcli or MegaCLI backend, running the
+// tool locally with the configured command timeout
+func newBackend(cfg *config.Config) (backend.Backend, error) {
+	megacliPath := viper.GetString("megacli_path")
+	if !config.IsValidMegaCLI(megacliPath) {
+		megacliPath = config.DiscoverMegaCLI()
+	}
+	return backend.New(
+		cfg.MegaRAID.Backend,
+		runner.Local{Timeout: viper.GetDuration("command_timeout")},
+		viper.GetString("storcli_path"),
+		megacliPath,
+		cfg.MegaRAID.Controllers,
+	)
+}
+
+// newCollector builds the collector on top of the configured backend
+func newCollector(cfg *config.Config) (*collector.MegaRAIDCollector, error) {
+	b, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Collecting with the %s backend", b.Name())
+	return collector.NewMegaRAIDCollector(b, cfg), nil
+}
+
+func run(opts *options) error {
+	cfg, err := setup(opts)
+	if err != nil {
+		return err
+	}
 
 	log.WithFields(logrus.Fields{
 		"version":      version,
 		"port":         viper.GetInt("port"),
 		"megacli_path": viper.GetString("megacli_path"),
+		"storcli_path": viper.GetString("storcli_path"),
 		"timeout":      viper.GetString("command_timeout"),
 	}).Info("Starting MegaRAID exporter")
 
-	// Verify megacli64 is available
-	if err := verifyMegaCLI(viper.GetString("megacli_path")); err != nil {
-		return fmt.Errorf("MegaCLI verification failed: %v", err)
+	b, err := newBackend(cfg)
+	if err != nil {
+		return err
 	}
 
-	// Create and register collector
-	collector := NewMegaRAIDCollector(
-		viper.GetString("megacli_path"),
-		viper.GetDuration("command_timeout"),
-		log,
-	)
-	
-	prometheus.MustRegister(collector)
-	
+	// Verify the storcli or megacli64 binary is available
+	log.Infof("Verifying %s at: %s", b.Name(), b.Path())
+	if err := verifyMegaCLI(b.Path()); err != nil {
+		return fmt.Errorf("%s verification failed (see megaraid-exporter doctor): %v", b.Name(), err)
+	}
+
+	filter, err := metricfilter.New(cfg.Metrics.Allow, cfg.Metrics.Deny)
+	if err != nil {
+		return fmt.Errorf("invalid metric filter: %v", err)
+	}
+
+	store, err := state.Open(cfg.State.Directory)
+	if err != nil {
+		return err
+	}
+	if store.Path() != "" {
+		log.Infof("Keeping state in %s", store.Path())
+	}
+
+	// States are tracked across background collections, so changes between
+	// scrapes are not lost
+	tracker := history.New(store)
+	scorer, err := risk.New(cfg.Risk, store)
+	if err != nil {
+		return fmt.Errorf("invalid risk config: %v", err)
+	}
+	thresholds, err := threshold.New(cfg.Thresholds)
+	if err != nil {
+		return fmt.Errorf("invalid thresholds config: %v", err)
+	}
+	megaraid := collector.NewMegaRAIDCollector(b, cfg).WithHistory(tracker).WithRisk(scorer).WithThresholds(thresholds)
+
+	// Handle graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Follow the kernel log for controller resets that happen between scrapes
+	var kernel prometheus.Collector
+	if path := viper.GetString("kmsg_path"); path != "" && cfg.MegaRAID.Features.KernelLog {
+		watcher := kmsg.NewWatcher(path, store)
+		if err := watcher.Start(ctx); err != nil {
+			log.Warnf("Kernel log watcher disabled: %v", err)
+		} else {
+			kernel = kmsg.NewCollector(watcher, cfg.Metrics.Namespace)
+		}
+	}
+
+	// Collect in the background so readiness reflects the controller even
+	// when nobody scrapes. Scrapes export the last background collection, so
+	// the CLI tool never runs twice at once.
+	background, err := poller.New(b, megaraid.Sections(), cfg.Scraping.Interval)
+	if err != nil {
+		return fmt.Errorf("invalid scraping config: %v", err)
+	}
+
+	// Static labels from the config are attached to every exporter metric
+	handler := collector.NewHandler(megaraid.WithPoller(background), kernel, cfg.Metrics.Labels, filter)
+	if cfg.Notifications.Enabled() {
+		hostname, _ := os.Hostname()
+		notifier, err := notify.New(cfg.Notifications, hostname, store)
+		if err != nil {
+			return fmt.Errorf("invalid notifications config: %v", err)
+		}
+		notifier.Start(ctx)
+		background.OnCollect(notifier.Observe)
+	}
+	background.OnCollect(tracker.Observe)
+	background.OnCollect(scorer.Observe)
+	background.Start(ctx)
+	health := collector.NewHealthHandler(background, b.Path(), cfg.Scraping.ReadyIntervals)
+
 	// Setup HTTP server
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
-	mux.HandleFunc("/health", healthHandler)
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `<html>
-<head><title>MegaRAID Exporter</title></head>
-<body>
-<h1>MegaRAID Exporter</h1>
-<p><a href="/metrics">Metrics</a></p>
-<p><a href="/health">Health</a></p>
-<p>Version: %s</p>
-</body>
-</html>`, version)
-	})
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler))
+	mux.Handle("/probe", collector.NewProbeHandler(megaraid, cfg.Probe, cfg.Metrics.Labels, filter))
+	mux.HandleFunc("/-/healthy", health.Healthy)
+	mux.HandleFunc("/-/ready", health.Ready)
+	mux.HandleFunc("/health", health.Ready)
+	mux.Handle(api.Prefix, api.NewHandler(background, scorer))
+	dashboard := ui.NewHandler(background, thresholds, version, "/metrics")
+	mux.HandleFunc(ui.EnclosurePrefix, dashboard.Enclosure)
+	mux.Handle("/", dashboard)
 
+	listenAddress := fmt.Sprintf(":%d", viper.GetInt("port"))
+	webConfigFile := viper.GetString("web_config_file")
+	// Probes run the CLI tool on the target while the client waits
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", viper.GetInt("port")),
 		Handler:      mux,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: viper.GetDuration("command_timeout") + 15*time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Handle graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -149,9 +307,15 @@ func run(configFile string, port int, megacliPath, logLevel string, timeout int)
 		cancel()
 	}()
 
-	log.Infof("HTTP server listening on :%d", viper.GetInt("port"))
-	
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	log.Infof("HTTP server listening on %s", listenAddress)
+
+	// TLS and basic auth come from the exporter-toolkit web config file
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &[]string{listenAddress},
+		WebConfigFile:      &webConfigFile,
+	}
+	logger := kitlog.NewJSONLogger(kitlog.NewSyncWriter(os.Stderr))
+	if err := web.ListenAndServe(server, webFlags, logger); err != http.ErrServerClosed {
 		return fmt.Errorf("HTTP server error: %v", err)
 	}
 
@@ -160,37 +324,7 @@ func run(configFile string, port int, megacliPath, logLevel string, timeout int)
 	return nil
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","version":"%s"}`, version)
-}
-
+// verifyMegaCLI checks that the CLI tool at path exists and is executable
 func verifyMegaCLI(path string) error {
-	// Implementation would verify megacli64 is executable and working
-	log.Infof("Verifying MegaCLI at: %s", path)
-	return nil
-}
-
-// Placeholder collector - actual implementation would be in separate files
-type MegaRAIDCollector struct {
-	megacliPath string
-	timeout     time.Duration
-	logger      *logrus.Logger
-}
-
-func NewMegaRAIDCollector(path string, timeout time.Duration, logger *logrus.Logger) *MegaRAIDCollector {
-	return &MegaRAIDCollector{
-		megacliPath: path,
-		timeout:     timeout,
-		logger:      logger,
-	}
-}
-
-func (c *MegaRAIDCollector) Describe(ch chan<- *prometheus.Desc) {
-	// Implementation needed
-}
-
-func (c *MegaRAIDCollector) Collect(ch chan<- prometheus.Metric) {
-	// Implementation needed
+	return backend.CheckTool(path)
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+func newStatusCommand(opts *options) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Print a summary of controllers, virtual drives, physical drives and batteries",
+		Long: `Collect once with the same backend as the exporter and print a summary,
+with anything that needs attention highlighted, including temperatures and
+error counters above the configured thresholds. --json prints the normalized
+snapshot instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(opts, jsonOutput)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the snapshot as JSON")
+
+	return cmd
+}
+
+func runStatus(opts *options, jsonOutput bool) error {
+	cfg, err := setup(opts)
+	if err != nil {
+		return err
+	}
+
+	thresholds, err := threshold.New(cfg.Thresholds)
+	if err != nil {
+		return fmt.Errorf("invalid thresholds config: %v", err)
+	}
+	b, err := newBackend(cfg)
+	if err != nil {
+		return err
+	}
+	snapshot := b.Collect(backend.Sections)
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printStatus(os.Stdout, snapshot, thresholds, isTerminal(os.Stdout))
+	return nil
+}
+
+// isTerminal reports whether f is a terminal, so colours are only used interactively
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// painter colours text by severity. Every cell of a column is wrapped in
+// escape codes of the same length so tabwriter keeps the columns aligned.
+type painter bool
+
+func (p painter) paint(severity diskutil.Severity, text string) string {
+	if !p {
+		return text
+	}
+	code := "32"
+	switch severity {
+	case diskutil.SeverityWarning:
+		code = "33"
+	case diskutil.SeverityCritical:
+		code = "31"
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+func printStatus(out io.Writer, snapshot *diskutil.Snapshot, t *threshold.Evaluator, colour bool) {
+	p := painter(colour)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Backend: %s\tCollected: %s\n", snapshot.Backend, snapshot.CollectedAt.Format(time.RFC3339))
+
+	fmt.Fprintln(w, "\nCONTROLLER\tMODEL\tSERIAL\tFIRMWARE\tSTATUS\tROC TEMP")
+	for _, ctrl := range snapshot.Controllers {
+		status, severity := ctrl.ControllerStatus, diskutil.SeverityOK
+		if status == "" {
+			status = "-"
+		} else if !strings.EqualFold(status, "optimal") {
+			severity = diskutil.SeverityCritical
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			ctrl.AdapterIndex, ctrl.ProductName, dash(ctrl.SerialNumber), dash(ctrl.FWVersion),
+			p.paint(severity, status), p.temperature(t, threshold.ROCTemperature,
+				fmt.Sprintf("controller %d", ctrl.AdapterIndex), ctrl.ProductName, float64(ctrl.ROCTemperature)))
+	}
+
+	fmt.Fprintln(w, "\nCTL\tVD\tNAME\tRAID\tSIZE\tCACHE\tSTATE")
+	for _, vd := range snapshot.VirtualDrives {
+		state := vd.NormalizedState()
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			vd.AdapterIndex, vd.TargetId, dash(vd.Name), vd.RAID_Level, dash(vd.Size),
+			dash(vd.CurrentCachePolicy), p.paint(state.Severity(), string(state)))
+	}
+
+	fmt.Fprintln(w, "\nCTL\tSLOT\tDID\tMODEL\tINTF\tMEDIA\tSIZE\tTEMP\tERRORS\tSTATE")
+	for _, pd := range snapshot.PhysicalDrives {
+		state := pd.NormalizedState()
+		errors := fmt.Sprintf("%d/%d/%d", pd.MediaErrorCount, pd.OtherErrorCount, pd.PredictiveFailureCount)
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			pd.AdapterIndex, pd.Slot(), pd.DeviceId, dash(pd.Model), dash(pd.Pdtype), dash(pd.MediaType),
+			dash(pd.RawSize), p.temperature(t, threshold.DriveTemperature, fmt.Sprintf("pd %d/%s", pd.AdapterIndex, pd.Slot()),
+				pd.Model, diskutil.ParseTemperature(pd.DriveTemperature)), errors,
+			p.paint(state.Severity(), string(state)))
+	}
+
+	if len(snapshot.Batteries) > 0 {
+		fmt.Fprintln(w, "\nCTL\tTYPE\tMODEL\tTEMP\tSTATE")
+		for _, bbu := range snapshot.Batteries {
+			severity := diskutil.SeverityOK
+			if !strings.EqualFold(bbu.BatteryState, "optimal") {
+				severity = diskutil.SeverityWarning
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				bbu.AdapterIndex, bbu.Kind(), dash(bbu.BatteryType),
+				p.temperature(t, threshold.BBUTemperature, fmt.Sprintf("%s %d", bbu.Kind(), bbu.AdapterIndex),
+					bbu.BatteryType, float64(bbu.Temperature)),
+				p.paint(severity, dash(bbu.BatteryState)))
+		}
+	}
+	w.Flush()
+
+	problems := t.Problems(snapshot)
+	if len(problems) == 0 {
+		fmt.Fprintf(out, "\n%s\n", p.paint(diskutil.SeverityOK, "No problems found"))
+		return
+	}
+	fmt.Fprintf(out, "\nProblems:\n")
+	for _, problem := range problems {
+		label := strings.ToUpper(problem.Severity.String())
+		fmt.Fprintf(out, "  %s %s: %s\n", p.paint(problem.Severity, label), problem.Component, problem.Message)
+	}
+}
+
+// temperature formats a temperature coloured by its thresholds. A missing
+// temperature is painted too, to keep the column aligned.
+func (p painter) temperature(t *threshold.Evaluator, name, component, model string, temp float64) string {
+	severity := diskutil.SeverityOK
+	if temp > 0 {
+		severity = t.Check(name, component, model, temp)
+	}
+	return p.paint(severity, celsius(temp))
+}
+
+func dash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
+
+func celsius(temp float64) string {
+	if temp <= 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(temp, 'f', -1, 64) + "C"
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+	"github.com/yourusername/megaraid-exporter/pkg/collector"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+)
+
+func newTextfileCommand(opts *options) *cobra.Command {
+	var (
+		output     string
+		collectors []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "textfile",
+		Short: "Collect once and write metrics for the node_exporter textfile collector",
+		Long: `Collect metrics once, write them atomically to the output file and exit.
+Point --output into the node_exporter --collector.textfile.directory, e.g.
+/var/lib/node_exporter/textfile/megaraid.prom, and run from cron or a systemd timer.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTextfile(opts, output, collectors)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the metrics to (must end in .prom)")
+	cmd.Flags().StringSliceVar(&collectors, "collect", nil, "Sub-collectors to run (default: all enabled)")
+	cmd.MarkFlagRequired("output")
+
+	return cmd
+}
+
+func runTextfile(opts *options, output string, collectors []string) error {
+	if !strings.HasSuffix(output, ".prom") {
+		return fmt.Errorf("output file %s must end in .prom for the textfile collector", output)
+	}
+
+	cfg, err := setup(opts)
+	if err != nil {
+		return err
+	}
+
+	filter, err := metricfilter.New(cfg.Metrics.Allow, cfg.Metrics.Deny)
+	if err != nil {
+		return fmt.Errorf("invalid metric filter: %v", err)
+	}
+
+	megaraid, err := newCollector(cfg)
+	if err != nil {
+		return err
+	}
+
+	// The kernel log watcher needs a long-running process, so it is not used here
+	handler := collector.NewHandler(megaraid, nil, cfg.Metrics.Labels, filter)
+	gatherer, err := handler.Gatherer(collectors)
+	if err != nil {
+		return err
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %v", err)
+	}
+
+	if err := writeTextfile(output, families); err != nil {
+		return err
+	}
+	log.Infof("Wrote %d metric families to %s", len(families), output)
+
+	// The file is still written so node_exporter sees the failed collector
+	if !collector.ScrapeSucceeded(families) {
+		return fmt.Errorf("one or more collectors failed, see megaraid_scrape_collector_success")
+	}
+	return nil
+}
+
+// writeTextfile writes families to a temporary file next to path and renames
+// it into place, so node_exporter never reads a partial file
+func writeTextfile(path string, families []*dto.MetricFamily) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	for _, mf := range families {
+		if _, err := expfmt.MetricFamilyToText(tmp, mf); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write metrics: %v", err)
+		}
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %v", tmp.Name(), path, err)
+	}
+	return nil
+}
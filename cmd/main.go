@@ -1,19 +1,30 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"log"
 	"net/http"
+	"os"
+	"time"
 	
-	"github.com/manojsiriparthi/megaraid-exporter/config"
-	"github.com/manojsiriparthi/megaraid-exporter/pkg/megacli"
+	kitlog "github.com/go-kit/log"
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/collector"
+	"github.com/yourusername/megaraid-exporter/pkg/megacli"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 )
 
 func main() {
 	var (
 		listenAddress = flag.String("web.listen-address", ":8080", "Address to listen on for web interface and telemetry.")
 		megacliPath   = flag.String("megacli.path", "", "Path to MegaCli64 binary (auto-discovered if not specified)")
+		webConfigFile = flag.String("web.config.file", "", "Path to configuration file that can enable TLS or authentication.")
 	)
 	flag.Parse()
 
@@ -39,18 +50,38 @@ func main() {
 	log.Printf("Starting MegaRAID exporter on %s", *listenAddress)
 	log.Printf("Using MegaCLI path: %s", pathMonitor.GetPath())
 
+	// Readiness needs a recent successful collection, not just the binary
+	b, err := backend.New(backend.NameMegaCLI, runner.Local{}, "", pathMonitor.GetPath(), cfg.MegaRAID.Controllers)
+	if err != nil {
+		log.Fatalf("Failed to set up backend: %v", err)
+	}
+	background, err := poller.New(b, backend.Sections, cfg.Scraping.Interval)
+	if err != nil {
+		log.Fatalf("Invalid scraping config: %v", err)
+	}
+	background.Start(context.Background())
+	health := collector.NewHealthHandler(background, b.Path(), cfg.Scraping.ReadyIntervals)
+
 	// Setup HTTP handlers
 	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/-/healthy", health.Healthy)
+	http.HandleFunc("/-/ready", health.Ready)
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		status := pathMonitor.GetStatus()
+		w.Header().Set("Content-Type", "application/json")
 		if pathMonitor.IsHealthy() {
 			w.WriteHeader(http.StatusOK)
 		} else {
 			w.WriteHeader(http.StatusServiceUnavailable)
 		}
-		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(status)
 	})
 
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	// TLS and basic auth come from the exporter-toolkit web config file
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &[]string{*listenAddress},
+		WebConfigFile:      webConfigFile,
+	}
+	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
+	log.Fatal(web.ListenAndServe(&http.Server{}, webFlags, logger))
 }
@@ -1,14 +1,93 @@
 package config
 
 import (
+	"fmt"
 	"os"
-	"path/filepath"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/notify"
+	"github.com/yourusername/megaraid-exporter/pkg/risk"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	MegaCLIPath string
-	Port        string
-	LogLevel    string
+	MegaCLIPath string `yaml:"-"`
+	Port        string `yaml:"-"`
+	LogLevel    string `yaml:"-"`
+
+	Scraping ScrapingConfig `yaml:"scraping"`
+	MegaRAID MegaRAIDConfig `yaml:"megaraid"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Advanced AdvancedConfig `yaml:"advanced"`
+	Probe    ProbeConfig    `yaml:"probe"`
+
+	Notifications notify.Config    `yaml:"notifications"`
+	State         StateConfig      `yaml:"state"`
+	Risk          risk.Config      `yaml:"risk"`
+	Thresholds    threshold.Config `yaml:"thresholds"`
+}
+
+// StateConfig mirrors the state section of config.yaml
+type StateConfig struct {
+	// Directory keeps counters, transition history, kernel log positions and
+	// drive first-seen times across restarts. Empty keeps them in memory only.
+	Directory string `yaml:"directory"`
+}
+
+// ScrapingConfig mirrors the scraping section of config.yaml
+type ScrapingConfig struct {
+	// Interval is the time between background collections
+	Interval time.Duration `yaml:"interval"`
+	// ReadyIntervals is how many intervals the last successful collection
+	// may be old before /-/ready fails
+	ReadyIntervals int `yaml:"ready_intervals"`
+}
+
+// ProbeConfig mirrors the probe section of config.yaml, used by /probe to
+// collect from hosts that only allow SSH
+type ProbeConfig struct {
+	// Backend selects the tool on the remote hosts: storcli, megacli or
+	// auto, which uses storcli when it runs on the target
+	Backend string `yaml:"backend"`
+	// StorCLIPath and MegaCLIPath are the binaries on the remote hosts
+	StorCLIPath string           `yaml:"storcli_path"`
+	MegaCLIPath string           `yaml:"megacli_path"`
+	SSH         runner.SSHConfig `yaml:"ssh"`
+}
+
+// MegaRAIDConfig mirrors the megaraid section of config.yaml
+type MegaRAIDConfig struct {
+	// Backend selects the tool to collect with: storcli, megacli or auto
+	Backend     string `yaml:"backend"`
+	StorCLIPath string `yaml:"storcli_path"`
+	MegaCLIPath string `yaml:"megacli_path"`
+	// Controllers limits collection to these controller IDs, empty means all
+	Controllers []int `yaml:"controllers"`
+	// Features enables or disables whole groups of metrics
+	Features FeaturesConfig `yaml:"features"`
+}
+
+// FeaturesConfig mirrors megaraid.features in config.yaml
+type FeaturesConfig struct {
+	PhysicalDrives bool `yaml:"physical_drives"`
+	VirtualDrives  bool `yaml:"virtual_drives"`
+	BatteryBackup  bool `yaml:"battery_backup"`
+	ControllerInfo bool `yaml:"controller_info"`
+	KernelLog      bool `yaml:"kernel_log"`
+}
+
+// MetricsConfig mirrors the metrics section of config.yaml
+type MetricsConfig struct {
+	// Namespace prefixes every metric name, "megaraid" by default
+	Namespace string `yaml:"namespace"`
+	// Labels are added to every exported series
+	Labels map[string]string `yaml:"labels"`
+	// Allow and Deny are regexes matched against whole metric names. When
+	// Allow is set only matching metrics are exported; Deny wins over Allow.
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
 }
 
 // Common MegaCLI installation paths
@@ -20,11 +99,83 @@ var DefaultMegaCLIPaths = []string{
 	"/opt/lsi/MegaCLI/MegaCli64",
 }
 
+// AdvancedConfig mirrors the advanced section of config.yaml
+type AdvancedConfig struct {
+	// SkipDriveStates lists physical drive states that are not exported,
+	// either as reported by the CLI ("Unconfigured(bad)", "UBad") or normalized
+	SkipDriveStates []string `yaml:"skip_drive_states"`
+}
+
 func NewConfig() *Config {
 	return &Config{
 		Port:     "8080",
 		LogLevel: "info",
+		Scraping: ScrapingConfig{
+			Interval:       30 * time.Second,
+			ReadyIntervals: 3,
+		},
+		MegaRAID: MegaRAIDConfig{
+			Backend: "auto",
+			Features: FeaturesConfig{
+				PhysicalDrives: true,
+				VirtualDrives:  true,
+				BatteryBackup:  true,
+				ControllerInfo: true,
+				KernelLog:      true,
+			},
+		},
+		Metrics: MetricsConfig{
+			Namespace: "megaraid",
+		},
+		Probe: ProbeConfig{
+			Backend:     "storcli",
+			StorCLIPath: "/opt/MegaRAID/storcli/storcli64",
+			MegaCLIPath: "/opt/MegaRAID/MegaCli/MegaCli64",
+			SSH: runner.SSHConfig{
+				User:    "root",
+				Port:    22,
+				Timeout: 10 * time.Second,
+			},
+		},
+		Notifications: notify.Config{
+			MinSeverity: "ok",
+			DedupWindow: time.Hour,
+			Retries:     3,
+			RetryDelay:  10 * time.Second,
+			Timeout:     10 * time.Second,
+		},
+		Risk: risk.Config{
+			RateWindow:       24 * time.Hour,
+			TemperatureLimit: 55,
+			ExpectedLifetime: 5 * 365 * 24 * time.Hour,
+		},
+		Thresholds: threshold.Config{
+			Limits: map[string]threshold.Limit{
+				threshold.ControllerTemperature: {Warning: 85, Critical: 95},
+				threshold.ROCTemperature:        {Warning: 85, Critical: 95},
+				threshold.DriveTemperature:      {Warning: 50, Critical: 60},
+				threshold.BBUTemperature:        {Warning: 50, Critical: 60},
+			},
+		},
+	}
+}
+
+// LoadFile reads a YAML configuration file on top of the defaults
+func LoadFile(path string) (*Config, error) {
+	cfg := NewConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	if cfg.MegaRAID.MegaCLIPath != "" {
+		cfg.MegaCLIPath = cfg.MegaRAID.MegaCLIPath
+	}
+
+	return cfg, nil
 }
 
 func (c *Config) SetMegaCLIPath(path string) {
@@ -1,51 +1,65 @@
 #!/bin/bash
 # Temperature monitoring script for MegaRAID
 # Demonstrates direct metrics access without Prometheus
+#
+# The warning and critical levels are not set here: the exporter checks them
+# itself (the thresholds section of config.yaml) and exports how many values
+# exceed them as megaraid_threshold_exceeded.
 
-EXPORTER_URL="http://localhost:9272/metrics"
-CRITICAL_TEMP=75
-WARNING_TEMP=65
+EXPORTER_URL="${EXPORTER_URL:-http://localhost:9272/metrics}"
 
 echo "=== MegaRAID Temperature Monitor ==="
-echo "Critical threshold: ${CRITICAL_TEMP}°C"
-echo "Warning threshold: ${WARNING_TEMP}°C"
 echo
 
 # Get metrics
-METRICS=$(curl -s "$EXPORTER_URL")
+METRICS=$(curl -sf "$EXPORTER_URL")
 if [ $? -ne 0 ]; then
     echo "ERROR: Cannot connect to exporter at $EXPORTER_URL"
     exit 1
 fi
 
-# Controller temperatures
+# label prints the value of label $2 in the sample $1, wherever it is in the
+# label set (static labels from the config may come first)
+label() {
+    echo "$1" | grep -o "[{,]$2=\"[^\"]*\"" | cut -d'"' -f2
+}
+
+# exceeded prints how many values are above their $1 level
+exceeded() {
+    echo "$METRICS" | grep '^megaraid_threshold_exceeded{' | while IFS= read -r line; do
+        if [ "$(label "$line" severity)" = "$1" ]; then
+            echo "$line" | awk '{print $2}'
+        fi
+    done
+}
+
 echo "Controller Temperatures:"
-echo "$METRICS" | grep 'megaraid_controller_temperature{' | while IFS= read -r line; do
-    CONTROLLER=$(echo "$line" | grep -o 'controller="[^"]*"' | cut -d'"' -f2)
-    TEMP=$(echo "$line" | awk '{print $2}')
-    
-    if (( $(echo "$TEMP > $CRITICAL_TEMP" | bc -l) )); then
-        echo "  Controller $CONTROLLER: ${TEMP}°C [CRITICAL]"
-    elif (( $(echo "$TEMP > $WARNING_TEMP" | bc -l) )); then
-        echo "  Controller $CONTROLLER: ${TEMP}°C [WARNING]"
-    else
-        echo "  Controller $CONTROLLER: ${TEMP}°C [OK]"
-    fi
+echo "$METRICS" | grep '^megaraid_controller_temperature_celsius{' | while IFS= read -r line; do
+    echo "  Controller $(label "$line" controller): $(echo "$line" | awk '{print $2}')°C"
 done
 
 echo
 echo "Drive Temperatures:"
-echo "$METRICS" | grep 'megaraid_drive_temperature{' | while IFS= read -r line; do
-    CONTROLLER=$(echo "$line" | grep -o 'controller="[^"]*"' | cut -d'"' -f2)
-    ENCLOSURE=$(echo "$line" | grep -o 'enclosure="[^"]*"' | cut -d'"' -f2)
-    SLOT=$(echo "$line" | grep -o 'slot="[^"]*"' | cut -d'"' -f2)
-    TEMP=$(echo "$line" | awk '{print $2}')
-    
-    if (( $(echo "$TEMP > $CRITICAL_TEMP" | bc -l) )); then
-        echo "  Drive $CONTROLLER:$ENCLOSURE:$SLOT: ${TEMP}°C [CRITICAL]"
-    elif (( $(echo "$TEMP > $WARNING_TEMP" | bc -l) )); then
-        echo "  Drive $CONTROLLER:$ENCLOSURE:$SLOT: ${TEMP}°C [WARNING]"
-    else
-        echo "  Drive $CONTROLLER:$ENCLOSURE:$SLOT: ${TEMP}°C [OK]"
-    fi
+echo "$METRICS" | grep '^megaraid_pd_temperature_celsius{' | while IFS= read -r line; do
+    echo "  Drive $(label "$line" controller)/$(label "$line" enclosure_slot): $(echo "$line" | awk '{print $2}')°C"
+done
+
+echo
+echo "Battery Temperatures:"
+echo "$METRICS" | grep '^megaraid_bbu_temperature_celsius{' | while IFS= read -r line; do
+    echo "  $(label "$line" type) $(label "$line" controller): $(echo "$line" | awk '{print $2}')°C"
 done
+
+WARNING=$(exceeded warning)
+CRITICAL=$(exceeded critical)
+
+echo
+echo "Thresholds exceeded: ${CRITICAL:-0} critical, ${WARNING:-0} warning"
+echo "Run 'megaraid-exporter status' for the values above their levels."
+
+if [ "${CRITICAL:-0}" != "0" ]; then
+    exit 2
+elif [ "${WARNING:-0}" != "0" ]; then
+    exit 1
+fi
+exit 0
@@ -1,39 +1,145 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net/http"
-	"time"
+	"os"
+
+	kitlog "github.com/go-kit/log"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/api"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/collector"
+	"github.com/yourusername/megaraid-exporter/pkg/history"
+	"github.com/yourusername/megaraid-exporter/pkg/kmsg"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+	"github.com/yourusername/megaraid-exporter/pkg/notify"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/risk"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+	"github.com/yourusername/megaraid-exporter/pkg/ui"
 )
 
 var (
 	listenAddress = flag.String("web.listen-address", ":9216", "Address to listen on for web interface and telemetry.")
 	metricsPath   = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
 	storCliPath   = flag.String("storcli.path", "/usr/sbin/storcli64", "Path to storcli binary.")
-	interval      = flag.Duration("interval", 30*time.Second, "Interval between metric collections.")
+	megaCliPath   = flag.String("megacli.path", "", "Path to MegaCLI binary, used when storcli is not installed.")
+	backendName   = flag.String("backend", "", "Collection backend: storcli, megacli or auto (default from config, auto).")
+	interval      = flag.Duration("interval", 0, "Interval between background collections (default from config, 30s).")
+	configFile    = flag.String("config.file", "", "Path to configuration file.")
+	webConfigFile = flag.String("web.config.file", "", "Path to configuration file that can enable TLS or authentication.")
+	kmsgPath      = flag.String("kmsg.path", kmsg.DefaultPath, "Kernel log to follow for megaraid_sas events, empty to disable.")
+	stateDir      = flag.String("state.dir", "", "Directory to keep counters and history in across restarts (default from config, in memory only).")
 )
 
 func main() {
 	flag.Parse()
 
-	collector := NewMegaRAIDCollector(*storCliPath)
-	prometheus.MustRegister(collector)
+	cfg := config.NewConfig()
+	if *configFile != "" {
+		var err error
+		if cfg, err = config.LoadFile(*configFile); err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+	}
+
+	filter, err := metricfilter.New(cfg.Metrics.Allow, cfg.Metrics.Deny)
+	if err != nil {
+		log.Fatalf("Invalid metric filter: %v", err)
+	}
+
+	if *megaCliPath != "" {
+		cfg.SetMegaCLIPath(*megaCliPath)
+	}
+	if *backendName != "" {
+		cfg.MegaRAID.Backend = *backendName
+	}
+	b, err := backend.New(cfg.MegaRAID.Backend, runner.Local{}, *storCliPath, cfg.GetMegaCLIPath(), cfg.MegaRAID.Controllers)
+	if err != nil {
+		log.Fatalf("Failed to set up backend: %v", err)
+	}
+	if *stateDir != "" {
+		cfg.State.Directory = *stateDir
+	}
+	store, err := state.Open(cfg.State.Directory)
+	if err != nil {
+		log.Fatalf("Failed to open state: %v", err)
+	}
+
+	// States are tracked across background collections, so changes between
+	// scrapes are not lost
+	tracker := history.New(store)
+	scorer, err := risk.New(cfg.Risk, store)
+	if err != nil {
+		log.Fatalf("Invalid risk config: %v", err)
+	}
+	thresholds, err := threshold.New(cfg.Thresholds)
+	if err != nil {
+		log.Fatalf("Invalid thresholds config: %v", err)
+	}
+	megaraid := collector.NewMegaRAIDCollector(b, cfg).WithHistory(tracker).WithRisk(scorer).WithThresholds(thresholds)
+
+	var kernel prometheus.Collector
+	if *kmsgPath != "" && cfg.MegaRAID.Features.KernelLog {
+		watcher := kmsg.NewWatcher(*kmsgPath, store)
+		if err := watcher.Start(context.Background()); err != nil {
+			log.Printf("Kernel log watcher disabled: %v", err)
+		} else {
+			kernel = kmsg.NewCollector(watcher, cfg.Metrics.Namespace)
+		}
+	}
+
+	// Collect in the background so readiness reflects the controller even
+	// when nobody scrapes. Scrapes export the last background collection, so
+	// the CLI tool never runs twice at once.
+	if *interval != 0 {
+		cfg.Scraping.Interval = *interval
+	}
+	background, err := poller.New(b, megaraid.Sections(), cfg.Scraping.Interval)
+	if err != nil {
+		log.Fatalf("Invalid scraping config: %v", err)
+	}
+
+	// Static labels from the config are attached to every exporter metric
+	handler := collector.NewHandler(megaraid.WithPoller(background), kernel, cfg.Metrics.Labels, filter)
+	http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler))
+	http.Handle("/probe", collector.NewProbeHandler(megaraid, cfg.Probe, cfg.Metrics.Labels, filter))
+	if cfg.Notifications.Enabled() {
+		hostname, _ := os.Hostname()
+		notifier, err := notify.New(cfg.Notifications, hostname, store)
+		if err != nil {
+			log.Fatalf("Invalid notifications config: %v", err)
+		}
+		notifier.Start(context.Background())
+		background.OnCollect(notifier.Observe)
+	}
+	background.OnCollect(tracker.Observe)
+	background.OnCollect(scorer.Observe)
+	background.Start(context.Background())
+	health := collector.NewHealthHandler(background, b.Path(), cfg.Scraping.ReadyIntervals)
+	http.HandleFunc("/-/healthy", health.Healthy)
+	http.HandleFunc("/-/ready", health.Ready)
+	http.Handle(api.Prefix, api.NewHandler(background, scorer))
+	dashboard := ui.NewHandler(background, thresholds, "", *metricsPath)
+	http.HandleFunc(ui.EnclosurePrefix, dashboard.Enclosure)
+	http.Handle("/", dashboard)
 
-	http.Handle(*metricsPath, promhttp.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-			<head><title>MegaRAID Exporter</title></head>
-			<body>
-			<h1>MegaRAID Exporter</h1>
-			<p><a href='` + *metricsPath + `'>Metrics</a></p>
-			</body>
-			</html>`))
-	})
+	// TLS and basic auth come from the exporter-toolkit web config file
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &[]string{*listenAddress},
+		WebConfigFile:      webConfigFile,
+	}
+	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 
 	log.Printf("Starting MegaRAID exporter on %s", *listenAddress)
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	log.Fatal(web.ListenAndServe(&http.Server{}, webFlags, logger))
 }
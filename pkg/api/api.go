@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/risk"
+)
+
+// Prefix is the path the API is mounted under
+const Prefix = "/api/v1/"
+
+// Handler serves the last background snapshot as JSON:
+//
+//	/api/v1/controllers
+//	/api/v1/virtual-drives?controller=0&state=degraded
+//	/api/v1/physical-drives?state=failed&state=rebuild
+//	/api/v1/batteries
+//	/api/v1/risk
+//
+// controller and state may be repeated; state matches the normalized state
+// ("unconfigured_bad") or the state reported by the tool ("UBad").
+// /api/v1/risk lists the drives by failure risk, highest first, with the
+// contribution of every factor to the score.
+type Handler struct {
+	poller *poller.Poller
+	risk   *risk.Scorer
+}
+
+func NewHandler(p *poller.Poller, r *risk.Scorer) *Handler {
+	return &Handler{poller: p, risk: r}
+}
+
+type response struct {
+	CollectedAt time.Time   `json:"collected_at"`
+	Backend     string      `json:"backend"`
+	Data        interface{} `json:"data"`
+}
+
+type filter struct {
+	controllers map[int]bool
+	states      map[string]bool
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+
+	f, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snapshot := h.poller.Snapshot()
+	if snapshot == nil {
+		writeError(w, http.StatusServiceUnavailable, "no data collected yet")
+		return
+	}
+
+	var data interface{}
+	switch strings.TrimPrefix(r.URL.Path, Prefix) {
+	case "controllers":
+		data = filterControllers(snapshot.Controllers, f)
+	case "virtual-drives":
+		data = filterVirtualDrives(snapshot.VirtualDrives, f)
+	case "physical-drives":
+		data = filterPhysicalDrives(snapshot.PhysicalDrives, f)
+	case "batteries":
+		data = filterBatteries(snapshot.Batteries, f)
+	case "risk":
+		data = filterScores(h.risk.Scores(), f)
+	default:
+		writeError(w, http.StatusNotFound, "unknown resource")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response{
+		CollectedAt: snapshot.CollectedAt,
+		Backend:     snapshot.Backend,
+		Data:        data,
+	})
+}
+
+func parseFilter(r *http.Request) (filter, error) {
+	query := r.URL.Query()
+	f := filter{}
+	for _, value := range query["controller"] {
+		ctl, err := strconv.Atoi(value)
+		if err != nil {
+			return f, fmt.Errorf("invalid controller: %s", value)
+		}
+		if f.controllers == nil {
+			f.controllers = make(map[int]bool)
+		}
+		f.controllers[ctl] = true
+	}
+	for _, value := range query["state"] {
+		if f.states == nil {
+			f.states = make(map[string]bool)
+		}
+		f.states[strings.ToLower(value)] = true
+	}
+	return f, nil
+}
+
+func (f filter) match(controller int, states ...string) bool {
+	if f.controllers != nil && !f.controllers[controller] {
+		return false
+	}
+	if f.states == nil {
+		return true
+	}
+	for _, state := range states {
+		if f.states[strings.ToLower(state)] {
+			return true
+		}
+	}
+	return false
+}
+
+func filterControllers(controllers []*diskutil.ControllerStat, f filter) []*diskutil.ControllerStat {
+	result := []*diskutil.ControllerStat{}
+	for _, c := range controllers {
+		if f.match(c.AdapterIndex, c.ControllerStatus) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func filterVirtualDrives(vds []*diskutil.VirtualDriveStat, f filter) []*diskutil.VirtualDriveStat {
+	result := []*diskutil.VirtualDriveStat{}
+	for _, vd := range vds {
+		if f.match(vd.AdapterIndex, vd.State, string(vd.NormalizedState())) {
+			result = append(result, vd)
+		}
+	}
+	return result
+}
+
+func filterPhysicalDrives(pds []*diskutil.PhysicalDriveStat, f filter) []*diskutil.PhysicalDriveStat {
+	result := []*diskutil.PhysicalDriveStat{}
+	for _, pd := range pds {
+		if f.match(pd.AdapterIndex, pd.FirmwareState, string(pd.NormalizedState())) {
+			result = append(result, pd)
+		}
+	}
+	return result
+}
+
+func filterBatteries(batteries []*diskutil.BatteryBackupStat, f filter) []*diskutil.BatteryBackupStat {
+	result := []*diskutil.BatteryBackupStat{}
+	for _, b := range batteries {
+		if f.match(b.AdapterIndex, b.BatteryState) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func filterScores(scores []risk.Score, f filter) []risk.Score {
+	result := []risk.Score{}
+	for _, score := range scores {
+		if f.match(score.Controller, score.State) {
+			result = append(result, score)
+		}
+	}
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
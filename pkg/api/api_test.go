@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/risk"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+var collectedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fakeBackend returns two controllers with a degraded RAID1 on the second one
+type fakeBackend struct{}
+
+func (fakeBackend) Name() string             { return "storcli" }
+func (fakeBackend) Path() string             { return "/bin/true" }
+func (fakeBackend) Version() (string, error) { return "storcli 007.1017", nil }
+func (fakeBackend) Collect(sections []string) *diskutil.Snapshot {
+	return &diskutil.Snapshot{
+		CollectedAt: collectedAt,
+		Backend:     "storcli",
+		Controllers: []*diskutil.ControllerStat{
+			{AdapterIndex: 0, ProductName: "PERC H730 Mini", ControllerStatus: "Optimal"},
+			{AdapterIndex: 1, ProductName: "PERC H730P", ControllerStatus: "Needs Attention"},
+		},
+		VirtualDrives: []*diskutil.VirtualDriveStat{
+			{AdapterIndex: 0, TargetId: 0, State: "Optl"},
+			{AdapterIndex: 1, TargetId: 0, State: "Dgrd"},
+		},
+		PhysicalDrives: []*diskutil.PhysicalDriveStat{
+			{AdapterIndex: 0, EnclosureDeviceId: 32, SlotNumber: 0, SerialNumber: "A", FirmwareState: "Onln"},
+			{AdapterIndex: 1, EnclosureDeviceId: 32, SlotNumber: 0, SerialNumber: "B", FirmwareState: "Onln", MediaErrorCount: 20},
+			{AdapterIndex: 1, EnclosureDeviceId: 32, SlotNumber: 1, SerialNumber: "C", FirmwareState: "F"},
+		},
+		Batteries: []*diskutil.BatteryBackupStat{
+			{AdapterIndex: 0, BatteryType: "CVPM02", BatteryState: "Optimal"},
+		},
+	}
+}
+
+func newHandler(t *testing.T, collect bool) *Handler {
+	t.Helper()
+	p, err := poller.New(fakeBackend{}, backend.Sections, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scorer, err := risk.New(risk.Config{RateWindow: 24 * time.Hour, TemperatureLimit: 50}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.OnCollect(scorer.Observe)
+	if collect {
+		p.Collect()
+	}
+	return NewHandler(p, scorer)
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   int
+		// count is the number of items in data, err the error message
+		count int
+		err   string
+	}{
+		{name: "controllers", target: "controllers", code: http.StatusOK, count: 2},
+		{name: "controller filter", target: "controllers?controller=1", code: http.StatusOK, count: 1},
+		{name: "repeated controller filter", target: "controllers?controller=0&controller=1", code: http.StatusOK, count: 2},
+		{name: "virtual drives", target: "virtual-drives", code: http.StatusOK, count: 2},
+		{name: "normalized state", target: "virtual-drives?state=degraded", code: http.StatusOK, count: 1},
+		{name: "reported state", target: "virtual-drives?state=DGRD", code: http.StatusOK, count: 1},
+		{name: "physical drives", target: "physical-drives", code: http.StatusOK, count: 3},
+		{name: "repeated state", target: "physical-drives?state=failed&state=rebuild", code: http.StatusOK, count: 1},
+		{name: "both filters", target: "physical-drives?controller=0&state=failed", code: http.StatusOK, count: 0},
+		{name: "batteries", target: "batteries", code: http.StatusOK, count: 1},
+		{name: "risk", target: "risk", code: http.StatusOK, count: 3},
+		{name: "risk filter", target: "risk?controller=0", code: http.StatusOK, count: 1},
+		{name: "HEAD", method: http.MethodHead, target: "controllers", code: http.StatusOK},
+		{name: "invalid controller", target: "controllers?controller=first", code: http.StatusBadRequest, err: "invalid controller: first"},
+		{name: "unknown resource", target: "enclosures", code: http.StatusNotFound, err: "unknown resource"},
+		{name: "POST", method: http.MethodPost, target: "controllers", code: http.StatusMethodNotAllowed, err: "only GET is supported"},
+	}
+
+	h := newHandler(t, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(method, Prefix+tt.target, nil))
+
+			if rec.Code != tt.code {
+				t.Errorf("got status %d, want %d", rec.Code, tt.code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("got content type %q", ct)
+			}
+			if method == http.MethodHead {
+				return
+			}
+
+			if tt.err != "" {
+				var body map[string]string
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != tt.err {
+					t.Errorf("got %s, want error %q", rec.Body, tt.err)
+				}
+				return
+			}
+			var body struct {
+				CollectedAt time.Time                `json:"collected_at"`
+				Backend     string                   `json:"backend"`
+				Data        []map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response %s: %v", rec.Body, err)
+			}
+			if !body.CollectedAt.Equal(collectedAt) || body.Backend != "storcli" {
+				t.Errorf("got collected_at %v and backend %q", body.CollectedAt, body.Backend)
+			}
+			// An empty result is a list, not null
+			if body.Data == nil || len(body.Data) != tt.count {
+				t.Errorf("got data %s, want %d items", rec.Body, tt.count)
+			}
+		})
+	}
+}
+
+func TestServeHTTPFields(t *testing.T) {
+	h := newHandler(t, true)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Prefix+"physical-drives?state=failed", nil))
+	var drives struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &drives); err != nil || len(drives.Data) != 1 {
+		t.Fatalf("got %s: %v", rec.Body, err)
+	}
+	if drive := drives.Data[0]; drive["serial_number"] != "C" || drive["firmware_state"] != "F" || drive["adapter_index"] != 1.0 {
+		t.Errorf("unexpected drive %v", drive)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Prefix+"risk", nil))
+	var scores struct {
+		Data []risk.Score `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &scores); err != nil || len(scores.Data) != 3 {
+		t.Fatalf("got %s: %v", rec.Body, err)
+	}
+	// Highest risk first, with the factors that make up the score
+	for i := 1; i < len(scores.Data); i++ {
+		if scores.Data[i].Score > scores.Data[i-1].Score {
+			t.Errorf("scores are not sorted: %+v", scores.Data)
+		}
+	}
+	if top := scores.Data[0]; top.Score == 0 || len(top.Factors) == 0 {
+		t.Errorf("unexpected top score %+v", top)
+	}
+}
+
+func TestServeHTTPNoData(t *testing.T) {
+	h := newHandler(t, false)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Prefix+"controllers", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != "no data collected yet" {
+		t.Errorf("got %s", rec.Body)
+	}
+}
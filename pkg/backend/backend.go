@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+)
+
+// Sections of a snapshot that can be collected independently
+const (
+	SectionController = "controller"
+	SectionVD         = "vd"
+	SectionPD         = "pd"
+	SectionBBU        = "bbu"
+)
+
+// Sections lists every section, in collection order
+var Sections = []string{SectionController, SectionVD, SectionPD, SectionBBU}
+
+// Backend names accepted by New
+const (
+	NameAuto    = "auto"
+	NameStorCLI = "storcli"
+	NameMegaCLI = "megacli"
+)
+
+// Backend collects a normalized snapshot from one of the LSI command line tools.
+// Sections that fail are recorded in Snapshot.Errors; the others are still filled in.
+type Backend interface {
+	Name() string
+	// Path is the tool binary the backend runs
+	Path() string
+	Collect(sections []string) *diskutil.Snapshot
+	// Version returns the tool's version banner
+	Version() (string, error)
+}
+
+// New returns the named backend running its tool through r. "auto" picks
+// storcli when it is installed locally and falls back to MegaCLI.
+func New(name string, r runner.Runner, storCliPath, megaCliPath string, controllers []int) (Backend, error) {
+	switch name {
+	case NameAuto, "":
+		if isExecutable(storCliPath) || megaCliPath == "" {
+			return NewStorCLI(r, storCliPath, controllers), nil
+		}
+		return NewMegaCLI(r, megaCliPath, controllers), nil
+	case NameStorCLI:
+		return NewStorCLI(r, storCliPath, controllers), nil
+	case NameMegaCLI:
+		if megaCliPath == "" {
+			return nil, fmt.Errorf("megacli backend selected but no MegaCLI binary was found")
+		}
+		return NewMegaCLI(r, megaCliPath, controllers), nil
+	}
+	return nil, fmt.Errorf("unknown backend: %s", name)
+}
+
+func isExecutable(path string) bool {
+	return CheckTool(path) == nil
+}
+
+// CheckTool checks that the CLI tool at path exists and is executable
+func CheckTool(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if info.Mode()&0111 == 0 {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	return nil
+}
+
+// versionLine picks the line carrying the version out of a "-v" banner
+func versionLine(output []byte) string {
+	var first string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.Contains(strings.ToLower(line), "ver") {
+			return line
+		}
+		if first == "" {
+			first = line
+		}
+	}
+	return first
+}
+
+func wants(sections []string, section string) bool {
+	for _, s := range sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
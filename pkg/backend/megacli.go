@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+)
+
+// MegaCLI collects from the text output of the legacy MegaCli64 tool, for
+// hosts where storcli is not installed
+type MegaCLI struct {
+	runner      runner.Runner
+	path        string
+	controllers []int
+}
+
+func NewMegaCLI(r runner.Runner, path string, controllers []int) *MegaCLI {
+	return &MegaCLI{
+		runner:      r,
+		path:        path,
+		controllers: controllers,
+	}
+}
+
+func (m *MegaCLI) Name() string {
+	return NameMegaCLI
+}
+
+func (m *MegaCLI) Path() string {
+	return m.path
+}
+
+func (m *MegaCLI) Version() (string, error) {
+	output, err := m.runner.Run(m.path, "-v")
+	if err != nil {
+		return "", fmt.Errorf("failed to execute MegaCLI: %v", err)
+	}
+	return versionLine(output), nil
+}
+
+func (m *MegaCLI) Collect(sections []string) *diskutil.Snapshot {
+	snapshot := diskutil.NewSnapshot(m.Name())
+
+	if wants(sections, SectionController) {
+		if err := m.collectControllers(snapshot); err != nil {
+			snapshot.SetError(SectionController, err)
+		}
+	}
+	if wants(sections, SectionVD) {
+		if err := m.collectVirtualDrives(snapshot); err != nil {
+			snapshot.SetError(SectionVD, err)
+		}
+	}
+	if wants(sections, SectionPD) {
+		if err := m.collectPhysicalDrives(snapshot); err != nil {
+			snapshot.SetError(SectionPD, err)
+		}
+	}
+	if wants(sections, SectionBBU) {
+		if err := m.collectBatteries(snapshot); err != nil {
+			snapshot.SetError(SectionBBU, err)
+		}
+	}
+
+	return snapshot
+}
+
+func (m *MegaCLI) collectControllers(snapshot *diskutil.Snapshot) error {
+	output, err := m.run("-AdpAllInfo")
+	if err != nil {
+		return err
+	}
+	controllers, err := diskutil.ParseControllerInfo(output)
+	if err != nil {
+		return err
+	}
+	snapshot.Controllers = controllers
+	return nil
+}
+
+func (m *MegaCLI) collectVirtualDrives(snapshot *diskutil.Snapshot) error {
+	output, err := m.run("-LDInfo", "-Lall")
+	if err != nil {
+		return err
+	}
+	vds, err := diskutil.ParseVirtualDriveInfo(output)
+	if err != nil {
+		return err
+	}
+	for _, vd := range vds {
+		vd.RAID_Level = raidLevel(vd.RAID_Level)
+	}
+	snapshot.VirtualDrives = vds
+	return nil
+}
+
+func (m *MegaCLI) collectPhysicalDrives(snapshot *diskutil.Snapshot) error {
+	output, err := m.run("-PDList")
+	if err != nil {
+		return err
+	}
+	pds, err := diskutil.ParsePhysicalDriveInfo(output)
+	if err != nil {
+		return err
+	}
+	for _, pd := range pds {
+		// "279.396 GB [0x22ecb25c Sectors]", storcli prints only the size
+		if idx := strings.Index(pd.RawSize, "["); idx >= 0 {
+			pd.RawSize = strings.TrimSpace(pd.RawSize[:idx])
+		}
+	}
+	snapshot.PhysicalDrives = pds
+	return nil
+}
+
+func (m *MegaCLI) collectBatteries(snapshot *diskutil.Snapshot) error {
+	// Adapters without a BBU make MegaCLI exit non-zero, that is not an error
+	output, err := m.run("-AdpBbuCmd", "-GetBbuStatus")
+	if err != nil {
+		return nil
+	}
+	batteries, err := diskutil.ParseBatteryInfo(output)
+	if err != nil {
+		return err
+	}
+	snapshot.Batteries = batteries
+	return nil
+}
+
+// adapterArg returns "-aALL" or the configured adapters as "-a0,1"
+func (m *MegaCLI) adapterArg() string {
+	if len(m.controllers) == 0 {
+		return "-aALL"
+	}
+	ids := make([]string, 0, len(m.controllers))
+	for _, ctl := range m.controllers {
+		ids = append(ids, strconv.Itoa(ctl))
+	}
+	return "-a" + strings.Join(ids, ",")
+}
+
+func (m *MegaCLI) run(args ...string) (string, error) {
+	args = append(args, m.adapterArg(), "-NoLog")
+	output, err := m.runner.Run(m.path, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute MegaCLI: %v", err)
+	}
+	return string(output), nil
+}
+
+// raidLevel turns MegaCLI's "Primary-1, Secondary-3, RAID Level Qualifier-0"
+// into storcli's spelling, "RAID10"
+func raidLevel(level string) string {
+	var primary, secondary, qualifier int
+	if _, err := fmt.Sscanf(level, "Primary-%d, Secondary-%d, RAID Level Qualifier-%d", &primary, &secondary, &qualifier); err != nil {
+		return level
+	}
+	if secondary == 3 {
+		return fmt.Sprintf("RAID%d0", primary)
+	}
+	return fmt.Sprintf("RAID%d", primary)
+}
@@ -0,0 +1,386 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+)
+
+type storCliResponse struct {
+	Controllers []storCliController `json:"Controllers"`
+}
+
+type storCliController struct {
+	CommandStatus struct {
+		Controller int    `json:"Controller"`
+		Status     string `json:"Status"`
+	} `json:"Command Status"`
+	ResponseData storCliData `json:"Response Data"`
+}
+
+type storCliData struct {
+	Basics         *storCliBasics  `json:"Basics,omitempty"`
+	Version        *storCliVersion `json:"Version,omitempty"`
+	Status         *storCliStatus  `json:"Status,omitempty"`
+	HwCfg          *storCliHwCfg   `json:"HwCfg,omitempty"`
+	VDList         []storCliVD     `json:"VD LIST,omitempty"`
+	PDList         []storCliPD     `json:"PD LIST,omitempty"`
+	BBUInfo        []storCliBBU    `json:"BBU_Info,omitempty"`
+	CacheVaultInfo []storCliBBU    `json:"Cachevault_Info,omitempty"`
+}
+
+// storCliBasics, storCliVersion, storCliStatus and storCliHwCfg are
+// sections of "/cx show all J"
+type storCliBasics struct {
+	Model        string `json:"Model"`
+	SerialNumber string `json:"Serial Number"`
+}
+
+type storCliVersion struct {
+	FirmwarePackageBuild string `json:"Firmware Package Build"`
+	FirmwareVersion      string `json:"Firmware Version"`
+	BiosVersion          string `json:"Bios Version"`
+}
+
+type storCliStatus struct {
+	ControllerStatus string `json:"Controller Status"`
+}
+
+// storCliHwCfg temperatures are numbers, and missing without a sensor
+type storCliHwCfg struct {
+	ROCTemperature  json.RawMessage `json:"ROC temperature(Degree Celsius)"`
+	CtrlTemperature json.RawMessage `json:"Ctrl temperature(Degree Celsius)"`
+}
+
+type storCliVD struct {
+	DGVD   string `json:"DG/VD"`
+	Type   string `json:"TYPE"`
+	State  string `json:"State"`
+	Access string `json:"Access"`
+	Cache  string `json:"Cache"`
+	Size   string `json:"Size"`
+	Name   string `json:"Name"`
+}
+
+// storCliPD is a row of the PD list. It has no error counters or
+// temperature, those are in the per-drive details.
+type storCliPD struct {
+	EIDSlt string `json:"EID:Slt"`
+	DID    int    `json:"DID"`
+	State  string `json:"State"`
+	// DG is the drive group number, or "-" for drives outside of one
+	DG    json.RawMessage `json:"DG"`
+	Size  string          `json:"Size"`
+	Intf  string          `json:"Intf"`
+	Med   string          `json:"Med"`
+	SED   string          `json:"SED"`
+	PI    string          `json:"PI"`
+	SeSz  string          `json:"SeSz"`
+	Model string          `json:"Model"`
+	Sp    string          `json:"Sp"`
+	Type  string          `json:"Type"`
+}
+
+// storCliPDDetail is the part of "/cx/eall/sall show all J" that the PD
+// list lacks. The response data is keyed by drive, e.g.
+// "Drive /c0/e252/s0 - Detailed Information", which holds
+// "Drive /c0/e252/s0 Device attributes" and "Drive /c0/e252/s0 State".
+// The counters are numbers.
+type storCliPDDetail struct {
+	SerialNumber      string          `json:"SN"`
+	WWN               string          `json:"WWN"`
+	FirmwareRevision  string          `json:"Firmware Revision"`
+	SMARTAlert        string          `json:"S.M.A.R.T alert flagged by drive"`
+	MediaErrors       json.RawMessage `json:"Media Error Count"`
+	OtherErrors       json.RawMessage `json:"Other Error Count"`
+	PredictiveFailure json.RawMessage `json:"Predictive Failure Count"`
+	Temperature       string          `json:"Drive Temperature"`
+}
+
+// storCliBBU is shared by "/cx/bbu show" and "/cx/cv show" (CacheVault) output
+type storCliBBU struct {
+	Model   string `json:"Model"`
+	State   string `json:"State"`
+	Temp    string `json:"Temp"`
+	MfgDate string `json:"MfgDate"`
+}
+
+// StorCLI collects from storcli's JSON output
+type StorCLI struct {
+	runner      runner.Runner
+	path        string
+	controllers []int
+}
+
+func NewStorCLI(r runner.Runner, path string, controllers []int) *StorCLI {
+	return &StorCLI{
+		runner:      r,
+		path:        path,
+		controllers: controllers,
+	}
+}
+
+func (s *StorCLI) Name() string {
+	return NameStorCLI
+}
+
+func (s *StorCLI) Path() string {
+	return s.path
+}
+
+func (s *StorCLI) Version() (string, error) {
+	output, err := s.runner.Run(s.path, "-v")
+	if err != nil {
+		return "", fmt.Errorf("failed to execute storcli: %v", err)
+	}
+	return versionLine(output), nil
+}
+
+func (s *StorCLI) Collect(sections []string) *diskutil.Snapshot {
+	snapshot := diskutil.NewSnapshot(s.Name())
+
+	// Controller details and the VD and PD lists come from the same storcli
+	// call, a failing controller fails all three
+	wantCtl, wantVD, wantPD := wants(sections, SectionController), wants(sections, SectionVD), wants(sections, SectionPD)
+	if wantCtl || wantVD || wantPD {
+		if err := s.collectControllers(snapshot, wantCtl, wantVD, wantPD); err != nil {
+			for _, section := range []string{SectionController, SectionVD, SectionPD} {
+				if wants(sections, section) {
+					snapshot.SetError(section, err)
+				}
+			}
+		}
+	}
+
+	if wants(sections, SectionBBU) {
+		s.collectBatteries(snapshot)
+	}
+
+	return snapshot
+}
+
+// controllerTargets returns the storcli selectors for the configured controllers
+func (s *StorCLI) controllerTargets() []string {
+	if len(s.controllers) == 0 {
+		return []string{"/call"}
+	}
+	targets := make([]string, 0, len(s.controllers))
+	for _, ctl := range s.controllers {
+		targets = append(targets, fmt.Sprintf("/c%d", ctl))
+	}
+	return targets
+}
+
+func (s *StorCLI) run(args ...string) (*storCliResponse, error) {
+	var response storCliResponse
+	if err := s.runInto(&response, args...); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// runInto runs storcli and decodes its JSON output into v
+func (s *StorCLI) runInto(v interface{}, args ...string) error {
+	output, err := s.runner.Run(s.path, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute storcli: %v", err)
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	return nil
+}
+
+// collectControllers runs "show all" against each configured controller.
+// A failing controller does not stop the others; the returned error lists
+// every failure.
+func (s *StorCLI) collectControllers(snapshot *diskutil.Snapshot, wantCtl, wantVD, wantPD bool) error {
+	var failures []string
+	for _, target := range s.controllerTargets() {
+		response, err := s.run(target, "show", "all", "J")
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", target, err))
+			continue
+		}
+		for _, ctrl := range response.Controllers {
+			ctl := ctrl.CommandStatus.Controller
+			if ctrl.CommandStatus.Status != "" && ctrl.CommandStatus.Status != "Success" {
+				failures = append(failures, fmt.Sprintf("/c%d: %s", ctl, ctrl.CommandStatus.Status))
+				continue
+			}
+			if wantCtl {
+				snapshot.Controllers = append(snapshot.Controllers, convertController(ctl, ctrl.ResponseData))
+			}
+			if wantVD {
+				for _, vd := range ctrl.ResponseData.VDList {
+					snapshot.VirtualDrives = append(snapshot.VirtualDrives, convertVD(ctl, vd))
+				}
+			}
+			if wantPD {
+				for _, pd := range ctrl.ResponseData.PDList {
+					snapshot.PhysicalDrives = append(snapshot.PhysicalDrives, convertPD(ctl, pd))
+				}
+			}
+		}
+		if wantPD {
+			s.collectDriveDetails(target, snapshot.PhysicalDrives)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// collectDriveDetails fills in the serial number, WWN, firmware, SMART alert,
+// error counters and temperature of drives in an enclosure, which only the
+// per-drive output has. Drives keep what the PD list reported when the
+// command fails.
+func (s *StorCLI) collectDriveDetails(target string, drives []*diskutil.PhysicalDriveStat) {
+	var response struct {
+		Controllers []struct {
+			ResponseData map[string]json.RawMessage `json:"Response Data"`
+		} `json:"Controllers"`
+	}
+	if err := s.runInto(&response, target+"/eall/sall", "show", "all", "J"); err != nil {
+		return
+	}
+
+	details := make(map[string]storCliPDDetail)
+	for _, ctrl := range response.Controllers {
+		for key, raw := range ctrl.ResponseData {
+			if !strings.HasSuffix(key, " - Detailed Information") {
+				continue
+			}
+			drive := strings.TrimSuffix(key, " - Detailed Information")
+			var sections map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &sections); err != nil {
+				continue
+			}
+			// Both sections decode into the same struct, each filling its own fields
+			var detail storCliPDDetail
+			for _, name := range []string{drive + " Device attributes", drive + " State"} {
+				if section, ok := sections[name]; ok {
+					json.Unmarshal(section, &detail)
+				}
+			}
+			details[strings.TrimPrefix(drive, "Drive ")] = detail
+		}
+	}
+
+	for _, pd := range drives {
+		detail, ok := details[fmt.Sprintf("/c%d/e%d/s%d", pd.AdapterIndex, pd.EnclosureDeviceId, pd.SlotNumber)]
+		if !ok {
+			continue
+		}
+		pd.SerialNumber = strings.TrimSpace(detail.SerialNumber)
+		pd.WWN = strings.TrimSpace(detail.WWN)
+		pd.FirmwareLevel = strings.TrimSpace(detail.FirmwareRevision)
+		pd.SMARTAlertFlagged = strings.TrimSpace(detail.SMARTAlert)
+		pd.MediaErrorCount = diskutil.ParseCount(rawString(detail.MediaErrors))
+		pd.OtherErrorCount = diskutil.ParseCount(rawString(detail.OtherErrors))
+		pd.PredictiveFailureCount = diskutil.ParseCount(rawString(detail.PredictiveFailure))
+		pd.DriveTemperature = strings.TrimSpace(detail.Temperature)
+	}
+}
+
+func (s *StorCLI) collectBatteries(snapshot *diskutil.Snapshot) {
+	for _, target := range s.controllerTargets() {
+		// Controllers without a BBU or CacheVault fail these commands, that is not an error
+		if response, err := s.run(target+"/bbu", "show", "all", "J"); err == nil {
+			for _, ctrl := range response.Controllers {
+				for _, bbu := range ctrl.ResponseData.BBUInfo {
+					snapshot.Batteries = append(snapshot.Batteries, convertBBU(ctrl.CommandStatus.Controller, bbu))
+				}
+			}
+		}
+		if response, err := s.run(target+"/cv", "show", "all", "J"); err == nil {
+			for _, ctrl := range response.Controllers {
+				for _, cv := range ctrl.ResponseData.CacheVaultInfo {
+					snapshot.Batteries = append(snapshot.Batteries, convertBBU(ctrl.CommandStatus.Controller, cv))
+				}
+			}
+		}
+	}
+}
+
+func convertController(ctl int, data storCliData) *diskutil.ControllerStat {
+	ctrl := &diskutil.ControllerStat{AdapterIndex: ctl}
+	if data.Basics != nil {
+		ctrl.ProductName = strings.TrimSpace(data.Basics.Model)
+		ctrl.SerialNumber = strings.TrimSpace(data.Basics.SerialNumber)
+	}
+	if data.Version != nil {
+		// Same value as MegaCLI's "FW Version", so firmware changes are seen
+		// with either backend; older firmware only reports the package build
+		ctrl.FWVersion = strings.TrimSpace(data.Version.FirmwareVersion)
+		if ctrl.FWVersion == "" {
+			ctrl.FWVersion = strings.TrimSpace(data.Version.FirmwarePackageBuild)
+		}
+		ctrl.BIOSVersion = strings.TrimSpace(data.Version.BiosVersion)
+	}
+	if data.Status != nil {
+		ctrl.ControllerStatus = data.Status.ControllerStatus
+	}
+	if data.HwCfg != nil {
+		ctrl.ROCTemperature = int(diskutil.ParseTemperature(rawString(data.HwCfg.ROCTemperature)))
+		ctrl.ControllerTemperature = int(diskutil.ParseTemperature(rawString(data.HwCfg.CtrlTemperature)))
+	}
+	return ctrl
+}
+
+func convertVD(ctl int, vd storCliVD) *diskutil.VirtualDriveStat {
+	// "DG/VD" is "0/1", the VD number is the target id
+	targetId := 0
+	if _, id, found := strings.Cut(vd.DGVD, "/"); found {
+		targetId, _ = strconv.Atoi(id)
+	}
+	return &diskutil.VirtualDriveStat{
+		AdapterIndex:       ctl,
+		TargetId:           targetId,
+		Name:               vd.Name,
+		RAID_Level:         vd.Type,
+		Size:               vd.Size,
+		State:              vd.State,
+		AccessPolicy:       vd.Access,
+		CurrentCachePolicy: vd.Cache,
+	}
+}
+
+func convertPD(ctl int, pd storCliPD) *diskutil.PhysicalDriveStat {
+	// "EID:Slt" is "252:0", or " :0" for drives without an enclosure
+	enclosure, slot, _ := strings.Cut(pd.EIDSlt, ":")
+	enclosureId, _ := strconv.Atoi(strings.TrimSpace(enclosure))
+	slotNumber, _ := strconv.Atoi(strings.TrimSpace(slot))
+
+	return &diskutil.PhysicalDriveStat{
+		AdapterIndex:      ctl,
+		EnclosureDeviceId: enclosureId,
+		DeviceId:          pd.DID,
+		SlotNumber:        slotNumber,
+		Pdtype:            pd.Intf,
+		MediaType:         pd.Med,
+		RawSize:           pd.Size,
+		FirmwareState:     pd.State,
+		Model:             strings.TrimSpace(pd.Model),
+	}
+}
+
+// rawString returns a JSON number or string as text, without quotes
+func rawString(raw json.RawMessage) string {
+	return strings.Trim(string(raw), `"`)
+}
+
+func convertBBU(ctl int, bbu storCliBBU) *diskutil.BatteryBackupStat {
+	return &diskutil.BatteryBackupStat{
+		AdapterIndex:    ctl,
+		BatteryType:     bbu.Model,
+		BatteryState:    bbu.State,
+		Temperature:     int(diskutil.ParseTemperature(bbu.Temp)),
+		ManufactureDate: bbu.MfgDate,
+	}
+}
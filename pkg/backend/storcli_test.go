@@ -0,0 +1,259 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+// Output of storcli 007.1017 for a controller with a RAID1 of two drives
+// and an unconfigured drive, shortened to the sections the backend reads
+const storcliShowAll = `{
+"Controllers":[
+{
+	"Command Status" : {
+		"CLI Version" : "007.1017.0000.0000 May 10, 2019",
+		"Operating system" : "Linux 5.4.0-150-generic",
+		"Controller" : 0,
+		"Status" : "Success",
+		"Description" : "None"
+	},
+	"Response Data" : {
+		"Basics" : {
+			"Controller" : 0,
+			"Model" : "PERC H730 Mini",
+			"Serial Number" : "5CG00AA",
+			"Current Controller Date/Time" : "01/02/2026, 03:04:05",
+			"SAS Address" : "5d0946600aaaa000",
+			"PCI Address" : "00:02:00:00"
+		},
+		"Version" : {
+			"Firmware Package Build" : "25.5.9.0001",
+			"Firmware Version" : "4.300.00-8366",
+			"Bios Version" : "6.33.01.0_4.19.08.00_0x06120304",
+			"Driver Name" : "megaraid_sas",
+			"Driver Version" : "07.714.04.00-rc1"
+		},
+		"Status" : {
+			"Controller Status" : "Optimal",
+			"Memory Correctable Errors" : 0,
+			"Memory Uncorrectable Errors" : 0,
+			"ECC Bucket Count" : 0,
+			"BBU Status" : 0
+		},
+		"HwCfg" : {
+			"ChipRevision" : " C0",
+			"On Board Memory Size" : "1024MB",
+			"CacheVault Flash Size" : "1.812 GB",
+			"ROC temperature(Degree Celsius)" : 62
+		},
+		"Virtual Drives" : 1,
+		"VD LIST" : [
+			{
+				"DG/VD" : "0/0",
+				"TYPE" : "RAID1",
+				"State" : "Optl",
+				"Access" : "RW",
+				"Consist" : "Yes",
+				"Cache" : "RWBD",
+				"Cac" : "-",
+				"sCC" : "ON",
+				"Size" : "278.875 GB",
+				"Name" : "os"
+			}
+		],
+		"Physical Drives" : 3,
+		"PD LIST" : [
+			{
+				"EID:Slt" : "32:0",
+				"DID" : 0,
+				"State" : "Onln",
+				"DG" : 0,
+				"Size" : "278.875 GB",
+				"Intf" : "SAS",
+				"Med" : "HDD",
+				"SED" : "N",
+				"PI" : "N",
+				"SeSz" : "512B",
+				"Model" : "ST300MM0008     ",
+				"Sp" : "U",
+				"Type" : "-"
+			},
+			{
+				"EID:Slt" : "32:1",
+				"DID" : 1,
+				"State" : "Onln",
+				"DG" : 0,
+				"Size" : "278.875 GB",
+				"Intf" : "SAS",
+				"Med" : "HDD",
+				"SED" : "N",
+				"PI" : "N",
+				"SeSz" : "512B",
+				"Model" : "ST300MM0008     ",
+				"Sp" : "U",
+				"Type" : "-"
+			},
+			{
+				"EID:Slt" : "32:2",
+				"DID" : 2,
+				"State" : "UGood",
+				"DG" : "-",
+				"Size" : "278.875 GB",
+				"Intf" : "SAS",
+				"Med" : "HDD",
+				"SED" : "N",
+				"PI" : "N",
+				"SeSz" : "512B",
+				"Model" : "ST300MM0008     ",
+				"Sp" : "U",
+				"Type" : "-"
+			}
+		]
+	}
+}
+]
+}`
+
+// storcliDrive returns "/c0/eall/sall show all J" output for one drive
+func storcliDrive(slot int, serial string, mediaErrors, otherErrors, predictiveFailures int, temperature string) string {
+	drive := fmt.Sprintf("Drive /c0/e32/s%d", slot)
+	return fmt.Sprintf(`
+		"%[1]s" : [
+			{"EID:Slt" : "32:%[2]d", "DID" : %[2]d, "State" : "Onln", "DG" : 0, "Size" : "278.875 GB", "Intf" : "SAS", "Med" : "HDD", "SED" : "N", "PI" : "N", "SeSz" : "512B", "Model" : "ST300MM0008     ", "Sp" : "U", "Type" : "-"}
+		],
+		"%[1]s - Detailed Information" : {
+			"%[1]s State" : {
+				"Shield Counter" : 0,
+				"Media Error Count" : %[4]d,
+				"Other Error Count" : %[5]d,
+				"Drive Temperature" : "%[7]s",
+				"Predictive Failure Count" : %[6]d,
+				"S.M.A.R.T alert flagged by drive" : "No"
+			},
+			"%[1]s Device attributes" : {
+				"SN" : "        %[3]s",
+				"Manufacturer Id" : "SEAGATE ",
+				"Model Number" : "ST300MM0008     ",
+				"NAND Vendor" : "NA",
+				"WWN" : "5000C500AAAA000%[2]d",
+				"Firmware Revision" : "TT31    ",
+				"Raw size" : "279.396 GB [0x22ecb25c Sectors]",
+				"Device Speed" : "12.0Gb/s",
+				"Link Speed" : "12.0Gb/s"
+			},
+			"%[1]s Policies/Settings" : {
+				"Drive position" : "DriveGroup:0, Span:0, Row:%[2]d",
+				"Enclosure position" : "1",
+				"Sequence Number" : 2
+			},
+			"Inquiry Data" : "53 45 41 47 41 54 45 20"
+		}`, drive, slot, serial, mediaErrors, otherErrors, predictiveFailures, temperature)
+}
+
+var storcliDrives = `{
+"Controllers":[
+{
+	"Command Status" : {
+		"CLI Version" : "007.1017.0000.0000 May 10, 2019",
+		"Operating system" : "Linux 5.4.0-150-generic",
+		"Controller" : 0,
+		"Status" : "Success",
+		"Description" : "Show Drive Information Succeeded."
+	},
+	"Response Data" : {` +
+	storcliDrive(0, "S0K1AAAA", 0, 0, 0, " 36C (96.80 F)") + "," +
+	storcliDrive(1, "S0K1BBBB", 12, 3, 1, " 41C (105.80 F)") + `
+	}
+}
+]
+}`
+
+// fakeStorCLI answers storcli commands with fixed output, commands it does
+// not know fail
+type fakeStorCLI map[string]string
+
+func (f fakeStorCLI) Run(path string, args ...string) ([]byte, error) {
+	output, ok := f[strings.Join(args, " ")]
+	if !ok {
+		return nil, fmt.Errorf("exit status 1")
+	}
+	return []byte(output), nil
+}
+
+func TestStorCLICollect(t *testing.T) {
+	tests := []struct {
+		name  string
+		fake  fakeStorCLI
+		check func(t *testing.T, s *diskutil.Snapshot)
+	}{
+		{
+			name: "drive details",
+			fake: fakeStorCLI{"/call show all J": storcliShowAll, "/call/eall/sall show all J": storcliDrives},
+			check: func(t *testing.T, s *diskutil.Snapshot) {
+				if len(s.PhysicalDrives) != 3 {
+					t.Fatalf("got %d drives, want 3", len(s.PhysicalDrives))
+				}
+				healthy, failing, unconfigured := s.PhysicalDrives[0], s.PhysicalDrives[1], s.PhysicalDrives[2]
+				if healthy.SerialNumber != "S0K1AAAA" || healthy.WWN != "5000C500AAAA0000" || healthy.FirmwareLevel != "TT31" {
+					t.Errorf("unexpected drive attributes: %+v", healthy)
+				}
+				if healthy.DriveTemperature != "36C (96.80 F)" || diskutil.ParseTemperature(healthy.DriveTemperature) != 36 {
+					t.Errorf("got temperature %q, want 36C", healthy.DriveTemperature)
+				}
+				if failing.MediaErrorCount != 12 || failing.OtherErrorCount != 3 || failing.PredictiveFailureCount != 1 {
+					t.Errorf("got counters %d/%d/%d, want 12/3/1", failing.MediaErrorCount,
+						failing.OtherErrorCount, failing.PredictiveFailureCount)
+				}
+				if unconfigured.NormalizedState() != diskutil.PDStateUnconfiguredGood || unconfigured.SerialNumber != "" {
+					t.Errorf("unexpected unconfigured drive: %+v", unconfigured)
+				}
+			},
+		},
+		{
+			name: "controller and virtual drive",
+			fake: fakeStorCLI{"/call show all J": storcliShowAll},
+			check: func(t *testing.T, s *diskutil.Snapshot) {
+				if len(s.Controllers) != 1 || len(s.VirtualDrives) != 1 {
+					t.Fatalf("got %d controllers and %d virtual drives, want 1 and 1", len(s.Controllers), len(s.VirtualDrives))
+				}
+				ctrl := s.Controllers[0]
+				if ctrl.ProductName != "PERC H730 Mini" || ctrl.FWVersion != "4.300.00-8366" ||
+					ctrl.ControllerStatus != "Optimal" || ctrl.ROCTemperature != 62 {
+					t.Errorf("unexpected controller: %+v", ctrl)
+				}
+				if vd := s.VirtualDrives[0]; vd.TargetId != 0 || vd.NormalizedState() != diskutil.VDStateOptimal || vd.RAID_Level != "RAID1" {
+					t.Errorf("unexpected virtual drive: %+v", vd)
+				}
+			},
+		},
+		{
+			name: "drive details fail",
+			fake: fakeStorCLI{"/call show all J": storcliShowAll},
+			check: func(t *testing.T, s *diskutil.Snapshot) {
+				if len(s.PhysicalDrives) != 3 || len(s.Errors) != 0 {
+					t.Errorf("got %d drives and errors %v, want the PD list", len(s.PhysicalDrives), s.Errors)
+				}
+			},
+		},
+		{
+			name: "show all fails",
+			fake: fakeStorCLI{},
+			check: func(t *testing.T, s *diskutil.Snapshot) {
+				for _, section := range []string{SectionController, SectionVD, SectionPD} {
+					if s.Errors[section] == "" {
+						t.Errorf("no %s error in %v", section, s.Errors)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, NewStorCLI(tt.fake, "storcli64", nil).Collect([]string{SectionController, SectionVD, SectionPD}))
+		})
+	}
+}
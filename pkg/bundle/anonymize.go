@@ -0,0 +1,113 @@
+package bundle
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+// identifierKeys are the fields holding serial numbers, WWNs, SAS addresses
+// and host names in storcli JSON and MegaCLI text output. MegaCLI's inquiry
+// data holds the drive serial next to vendor, model and firmware, and is
+// replaced as a whole.
+const identifierKeys = `SN|Serial ?N(?:o|um|umber)|SerialNo|WWN|SAS Address(?:\(\d+\))?|Host ?[Nn]ame|Inquiry Data`
+
+var (
+	jsonIdentifierPattern = regexp.MustCompile(`"(?:` + identifierKeys + `)"\s*:\s*"([^"]+)"`)
+	textIdentifierPattern = regexp.MustCompile(`(?mi)^\s*(?:` + identifierKeys + `)\s*:\s*(\S[^\r\n]*?)\s*$`)
+)
+
+// Anonymizer replaces identifiers with keyed hashes. The same key maps an
+// identifier to the same hash, so bundles captured with one key can still be
+// compared drive by drive.
+type Anonymizer struct {
+	key         []byte
+	identifiers map[string]string
+}
+
+// NewAnonymizer returns an anonymizer keyed with salt, or with a random key
+// when salt is empty
+func NewAnonymizer(salt string) *Anonymizer {
+	key := []byte(salt)
+	if salt == "" {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Anonymizer{
+		key:         key,
+		identifiers: make(map[string]string),
+	}
+}
+
+// Add marks value as an identifier
+func (a *Anonymizer) Add(value string) {
+	value = strings.TrimSpace(value)
+	// Short values ("0", "N/A") would replace unrelated text
+	if len(value) < 4 || strings.EqualFold(value, "N/A") {
+		return
+	}
+	if _, ok := a.identifiers[value]; ok {
+		return
+	}
+	a.identifiers[value] = a.Hash(value)
+
+	// The short host name appears on its own too
+	if host, _, found := strings.Cut(value, "."); found {
+		a.Add(host)
+	}
+}
+
+// Hash returns the replacement for value
+func (a *Anonymizer) Hash(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// Collect finds the identifiers in one command output. It fails when the
+// drive serials in MegaCLI inquiry data cannot be found, as they may also
+// appear elsewhere in the output.
+func (a *Anonymizer) Collect(output []byte) error {
+	for _, pattern := range []*regexp.Regexp{jsonIdentifierPattern, textIdentifierPattern} {
+		for _, match := range pattern.FindAllSubmatch(output, -1) {
+			a.Add(string(match[1]))
+		}
+	}
+
+	// MegaCLI buries the drive serial in the inquiry data
+	if strings.Contains(string(output), "Inquiry Data") {
+		drives, err := diskutil.ParsePhysicalDriveInfo(string(output))
+		if err != nil {
+			return fmt.Errorf("failed to find drive serial numbers in inquiry data: %v", err)
+		}
+		for _, drive := range drives {
+			a.Add(drive.SerialNumber)
+		}
+	}
+	return nil
+}
+
+// Apply replaces every identifier found so far, longest first so that a
+// host name does not break up its own FQDN
+func (a *Anonymizer) Apply(data []byte) []byte {
+	values := make([]string, 0, len(a.identifiers))
+	for value := range a.identifiers {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	replacements := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		replacements = append(replacements, value, a.identifiers[value])
+	}
+	return []byte(strings.NewReplacer(replacements...).Replace(string(data)))
+}
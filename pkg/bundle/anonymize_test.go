@@ -0,0 +1,97 @@
+package bundle
+
+import (
+	"strings"
+	"testing"
+)
+
+const megacliPDList = `Adapter #0
+
+Enclosure Device ID: 32
+Slot Number: 0
+Device Id: 0
+WWN: 5000C500AAAA0000
+Media Error Count: 0
+Other Error Count: 0
+Predictive Failure Count: 0
+Raw Size: 931.512 GB [0x74706db0 Sectors]
+Firmware state: Online, Spun Up
+SAS Address(0): 0x5000c500aaaa0001
+Inquiry Data: SEAGATE ST1000NM0023    0004Z1Z0AAAA
+Drive Temperature :35C (95.00 F)
+`
+
+func TestAnonymize(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    bool
+		hidden []string
+		kept   []string
+	}{
+		{
+			name:   "megacli drive",
+			output: megacliPDList + "Drive 0004Z1Z0AAAA in slot 0\n",
+			hidden: []string{"Z1Z0AAAA", "5000C500AAAA0000", "0x5000c500aaaa0001", "SEAGATE ST1000NM0023"},
+			kept:   []string{"Enclosure Device ID: 32", "Firmware state: Online, Spun Up"},
+		},
+		{
+			name:   "storcli drive",
+			output: `{"Drive /c0/e32/s0 Device attributes":{"SN":"  Z1Z0AAAA","WWN":"5000C500AAAA0000","Model Number":"ST1000NM0023"}}`,
+			hidden: []string{"Z1Z0AAAA", "5000C500AAAA0000"},
+			kept:   []string{"ST1000NM0023"},
+		},
+		{
+			name:   "unparseable inquiry data",
+			output: strings.Replace(megacliPDList, "Media Error Count: 0", "Media Error Count: n/a", 1),
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{
+				Manifest: Manifest{Hostname: "db1.example.com"},
+				Outputs:  map[string][]byte{"commands/01-pdlist.txt": []byte(tt.output)},
+			}
+			err := b.Anonymize(NewAnonymizer("salt"))
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if b.Manifest.Anonymized || string(b.Outputs["commands/01-pdlist.txt"]) != tt.output {
+					t.Error("bundle was changed although anonymizing failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := string(b.Outputs["commands/01-pdlist.txt"])
+			for _, value := range tt.hidden {
+				if strings.Contains(output, value) {
+					t.Errorf("%q not anonymized:\n%s", value, output)
+				}
+			}
+			for _, value := range tt.kept {
+				if !strings.Contains(output, value) {
+					t.Errorf("%q missing:\n%s", value, output)
+				}
+			}
+			if !b.Manifest.Anonymized || strings.Contains(b.Manifest.Hostname, "db1") {
+				t.Errorf("manifest not anonymized: %+v", b.Manifest)
+			}
+		})
+	}
+}
+
+func TestAnonymizerIsKeyed(t *testing.T) {
+	a, b := NewAnonymizer("one"), NewAnonymizer("one")
+	if a.Hash("Z1Z0AAAA") != b.Hash("Z1Z0AAAA") {
+		t.Error("same key gave different hashes")
+	}
+	if a.Hash("Z1Z0AAAA") == NewAnonymizer("two").Hash("Z1Z0AAAA") {
+		t.Error("different keys gave the same hash")
+	}
+}
@@ -0,0 +1,209 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+)
+
+// manifestFile is the name of the manifest inside the archive
+const manifestFile = "manifest.json"
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Manifest describes how a support bundle was captured
+type Manifest struct {
+	CreatedAt       time.Time `json:"created_at"`
+	ExporterVersion string    `json:"exporter_version"`
+	Hostname        string    `json:"hostname"`
+	Backend         string    `json:"backend"`
+	ToolPath        string    `json:"tool_path"`
+	ToolVersion     string    `json:"tool_version"`
+	Controllers     []int     `json:"controllers,omitempty"`
+	Anonymized      bool      `json:"anonymized"`
+	Commands        []Command `json:"commands"`
+}
+
+// Command is one recorded tool invocation and the file holding its output
+type Command struct {
+	Args  []string `json:"args"`
+	File  string   `json:"file"`
+	Error string   `json:"error,omitempty"`
+}
+
+// Bundle is a support bundle: the raw output of every command a backend ran
+type Bundle struct {
+	Manifest Manifest
+	Outputs  map[string][]byte
+}
+
+// New builds a bundle from the commands recorded while collecting
+func New(manifest Manifest, records []runner.Record) *Bundle {
+	b := &Bundle{
+		Manifest: manifest,
+		Outputs:  make(map[string][]byte),
+	}
+	for i, record := range records {
+		name := unsafeFileChars.ReplaceAllString(strings.Join(record.Args, "_"), "_")
+		command := Command{
+			Args: record.Args,
+			File: fmt.Sprintf("commands/%02d-%s.txt", i+1, strings.Trim(name, "_-")),
+		}
+		if record.Err != nil {
+			command.Error = record.Err.Error()
+		}
+		b.Manifest.Commands = append(b.Manifest.Commands, command)
+		b.Outputs[command.File] = record.Output
+	}
+	return b
+}
+
+// Anonymize replaces serial numbers, WWNs and host names in every output
+// and in the manifest with keyed hashes. The bundle is left unchanged when
+// an output cannot be searched for identifiers, and must not be shared.
+func (b *Bundle) Anonymize(a *Anonymizer) error {
+	a.Add(b.Manifest.Hostname)
+	for file, output := range b.Outputs {
+		if err := a.Collect(output); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	for file, output := range b.Outputs {
+		b.Outputs[file] = a.Apply(output)
+	}
+	for i := range b.Manifest.Commands {
+		b.Manifest.Commands[i].Error = string(a.Apply([]byte(b.Manifest.Commands[i].Error)))
+	}
+	if b.Manifest.Hostname != "" {
+		b.Manifest.Hostname = a.Hash(b.Manifest.Hostname)
+	}
+	b.Manifest.Anonymized = true
+	return nil
+}
+
+// Snapshot parses the recorded output with the backend that produced it
+func (b *Bundle) Snapshot() (*diskutil.Snapshot, error) {
+	records := make([]runner.Record, 0, len(b.Manifest.Commands))
+	for _, command := range b.Manifest.Commands {
+		record := runner.Record{Args: command.Args, Output: b.Outputs[command.File]}
+		if command.Error != "" {
+			record.Err = errors.New(command.Error)
+		}
+		records = append(records, record)
+	}
+
+	path := b.Manifest.ToolPath
+	be, err := backend.New(b.Manifest.Backend, runner.NewReplay(records), path, path, b.Manifest.Controllers)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := be.Collect(backend.Sections)
+	snapshot.CollectedAt = b.Manifest.CreatedAt
+	return snapshot, nil
+}
+
+// WriteFile writes the bundle as a gzipped tarball
+func (b *Bundle) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.write(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+func (b *Bundle) write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, manifestFile, manifest, b.Manifest.CreatedAt); err != nil {
+		return err
+	}
+	for _, command := range b.Manifest.Commands {
+		if err := writeEntry(tw, command.File, b.Outputs[command.File], b.Manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// ReadFile reads a bundle written by WriteFile
+func ReadFile(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %v", path, err)
+	}
+	tr := tar.NewReader(gz)
+
+	b := &Bundle{Outputs: make(map[string][]byte)}
+	foundManifest := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle %s: %v", path, err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from bundle: %v", header.Name, err)
+		}
+		if header.Name == manifestFile {
+			if err := json.Unmarshal(data, &b.Manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse bundle manifest: %v", err)
+			}
+			foundManifest = true
+			continue
+		}
+		b.Outputs[header.Name] = data
+	}
+
+	if !foundManifest {
+		return nil, fmt.Errorf("%s is not a support bundle: %s is missing", path, manifestFile)
+	}
+	return b, nil
+}
@@ -0,0 +1,677 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/history"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/risk"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+// Sub-collector names, selectable per scrape with collect[] and enabled
+// through megaraid.features in the config
+const (
+	collectorController = backend.SectionController
+	collectorVD         = backend.SectionVD
+	collectorPD         = backend.SectionPD
+	collectorBBU        = backend.SectionBBU
+)
+
+var collectorNames = []string{collectorController, collectorVD, collectorPD, collectorBBU}
+
+type MegaRAIDCollector struct {
+	backend    backend.Backend
+	skipStates map[string]bool
+	enabled    map[string]bool
+	history    *history.Tracker     // nil when states are not tracked across collections
+	risk       *risk.Scorer         // nil when drives are not scored
+	thresholds *threshold.Evaluator // nil when no thresholds are checked
+	poller     *poller.Poller       // nil when every scrape runs the CLI tool
+	partial    bool                 // collect[] selected some of the enabled sub-collectors
+	names      []string             // fully qualified names of the metrics below
+
+	// Scrape metrics
+	scrapeSuccess     *prometheus.Desc
+	thresholdExceeded *prometheus.Desc
+	
+	// Controller metrics
+	controllerInfo   *prometheus.Desc
+	controllerStatus *prometheus.Desc
+	controllerTemp   *prometheus.Desc
+	
+	// Virtual Drive metrics
+	vdInfo   *prometheus.Desc
+	vdStatus *prometheus.Desc
+	vdState  *prometheus.Desc
+	vdSize   *prometheus.Desc
+	vdStateTransitions *prometheus.Desc
+	
+	// Physical Drive metrics
+	pdInfo        *prometheus.Desc
+	pdStatus      *prometheus.Desc
+	pdState       *prometheus.Desc
+	pdTemp        *prometheus.Desc
+	pdMediaErrors *prometheus.Desc
+	pdOtherErrors *prometheus.Desc
+	pdPredictiveFailures *prometheus.Desc
+	pdStateLastChange    *prometheus.Desc
+	pdReplaced           *prometheus.Desc
+	pdSlotAge            *prometheus.Desc
+	pdFailureRisk        *prometheus.Desc
+
+	// Battery backup metrics
+	bbuInfo   *prometheus.Desc
+	bbuStatus *prometheus.Desc
+	bbuTemp   *prometheus.Desc
+	bbuReplacementRequired *prometheus.Desc
+}
+
+func NewMegaRAIDCollector(b backend.Backend, cfg *config.Config) *MegaRAIDCollector {
+	namespace := cfg.Metrics.Namespace
+
+	// Skip states match both the raw CLI spelling and the normalized state
+	skipStates := make(map[string]bool)
+	for _, state := range cfg.Advanced.SkipDriveStates {
+		skipStates[strings.ToLower(state)] = true
+		if normalized := diskutil.ParsePDState(state); normalized != diskutil.PDStateUnknown {
+			skipStates[string(normalized)] = true
+		}
+	}
+
+	features := cfg.MegaRAID.Features
+	enabled := map[string]bool{
+		collectorController: features.ControllerInfo,
+		collectorVD:         features.VirtualDrives,
+		collectorPD:         features.PhysicalDrives,
+		collectorBBU:        features.BatteryBackup,
+	}
+
+	// names records every metric name, in the order the Descs are built
+	var names []string
+	newDesc := func(subsystem, name, help string, labels []string) *prometheus.Desc {
+		fqName := prometheus.BuildFQName(namespace, subsystem, name)
+		names = append(names, fqName)
+		return prometheus.NewDesc(fqName, help, labels, nil)
+	}
+
+	c := &MegaRAIDCollector{
+		backend:    b,
+		skipStates: skipStates,
+		enabled:    enabled,
+		scrapeSuccess: newDesc("scrape", "collector_success",
+			"Whether a sub-collector succeeded (1=success, 0=failure)",
+			[]string{"collector"},
+		),
+		thresholdExceeded: newDesc("", "threshold_exceeded",
+			"Number of temperatures and error counters at or above their warning or critical level",
+			[]string{"severity"},
+		),
+		controllerInfo: newDesc("controller", "info",
+			"Descriptive attributes of MegaRAID controller, value is always 1",
+			[]string{"controller", "model", "serial"},
+		),
+		controllerStatus: newDesc("controller", "status",
+			"Status of MegaRAID controller (1=optimal, 0=not optimal)",
+			[]string{"controller"},
+		),
+		controllerTemp: newDesc("controller", "temperature_celsius",
+			"Temperature of MegaRAID controller in Celsius",
+			[]string{"controller"},
+		),
+		vdInfo: newDesc("vd", "info",
+			"Descriptive attributes of virtual drive, value is always 1",
+			[]string{"controller", "vd", "name", "type", "access", "cache"},
+		),
+		vdStatus: newDesc("vd", "status",
+			"Status of virtual drive (1=optimal, 0=not optimal)",
+			[]string{"controller", "vd"},
+		),
+		vdState: newDesc("vd", "state",
+			"Current state of virtual drive, one series per state (1=current state)",
+			[]string{"controller", "vd", "state"},
+		),
+		vdSize: newDesc("vd", "size_bytes",
+			"Size of virtual drive in bytes",
+			[]string{"controller", "vd"},
+		),
+		vdStateTransitions: newDesc("vd", "state_transitions_total",
+			"State changes of virtual drive seen by the background collection since the exporter started",
+			[]string{"controller", "vd", "from", "to"},
+		),
+		pdInfo: newDesc("pd", "info",
+			"Descriptive attributes of physical drive, value is always 1",
+			[]string{"controller", "enclosure_slot", "device_id", "model", "serial", "wwn", "interface", "media", "size"},
+		),
+		pdStatus: newDesc("pd", "status",
+			"Status of physical drive (1=online, 0=not online)",
+			[]string{"controller", "enclosure_slot"},
+		),
+		pdState: newDesc("pd", "state",
+			"Current state of physical drive, one series per state (1=current state)",
+			[]string{"controller", "enclosure_slot", "state"},
+		),
+		pdTemp: newDesc("pd", "temperature_celsius",
+			"Temperature of physical drive in Celsius",
+			[]string{"controller", "enclosure_slot"},
+		),
+		pdMediaErrors: newDesc("pd", "media_errors_total",
+			"Total media errors on physical drive, drive is its WWN or serial number so that a replacement starts a new series",
+			[]string{"controller", "enclosure_slot", "drive"},
+		),
+		pdOtherErrors: newDesc("pd", "other_errors_total",
+			"Total other errors on physical drive, drive is its WWN or serial number",
+			[]string{"controller", "enclosure_slot", "drive"},
+		),
+		pdPredictiveFailures: newDesc("pd", "predictive_failures_total",
+			"Total predictive failures on physical drive, drive is its WWN or serial number",
+			[]string{"controller", "enclosure_slot", "drive"},
+		),
+		pdStateLastChange: newDesc("pd", "state_last_change_timestamp_seconds",
+			"Unix timestamp of the last state change of physical drive, or of when the exporter first saw it",
+			[]string{"controller", "enclosure_slot"},
+		),
+		pdReplaced: newDesc("pd", "replaced_total",
+			"Times a drive with a different WWN or serial number showed up in the slot",
+			[]string{"controller", "enclosure_slot"},
+		),
+		pdSlotAge: newDesc("pd", "slot_age_seconds",
+			"Seconds since the drive in the slot was first seen there",
+			[]string{"controller", "enclosure_slot"},
+		),
+		pdFailureRisk: newDesc("pd", "failure_risk_score",
+			"Failure risk of physical drive from 0 to 100, combining error counters, predictive failures, SMART alert, error rate, temperature excursions and time since first seen; see /api/v1/risk",
+			[]string{"controller", "enclosure_slot"},
+		),
+		bbuInfo: newDesc("bbu", "info",
+			"Descriptive attributes of battery backup unit or CacheVault, value is always 1",
+			[]string{"controller", "type", "model", "manufacture_date"},
+		),
+		bbuStatus: newDesc("bbu", "status",
+			"Status of battery backup unit or CacheVault (1=optimal, 0=not optimal)",
+			[]string{"controller", "type"},
+		),
+		bbuTemp: newDesc("bbu", "temperature_celsius",
+			"Temperature of battery backup unit or CacheVault in Celsius",
+			[]string{"controller", "type"},
+		),
+		bbuReplacementRequired: newDesc("bbu", "replacement_required",
+			"Whether the controller asks for the battery backup unit or CacheVault to be replaced (1=yes, 0=no)",
+			[]string{"controller", "type"},
+		),
+	}
+	c.names = names
+	return c
+}
+
+func (c *MegaRAIDCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.scrapeSuccess
+	ch <- c.thresholdExceeded
+	ch <- c.controllerInfo
+	ch <- c.controllerStatus
+	ch <- c.controllerTemp
+	ch <- c.vdInfo
+	ch <- c.vdStatus
+	ch <- c.vdState
+	ch <- c.vdSize
+	ch <- c.vdStateTransitions
+	ch <- c.pdInfo
+	ch <- c.pdStatus
+	ch <- c.pdState
+	ch <- c.pdTemp
+	ch <- c.pdMediaErrors
+	ch <- c.pdOtherErrors
+	ch <- c.pdPredictiveFailures
+	ch <- c.pdStateLastChange
+	ch <- c.pdReplaced
+	ch <- c.pdSlotAge
+	ch <- c.pdFailureRisk
+	ch <- c.bbuInfo
+	ch <- c.bbuStatus
+	ch <- c.bbuTemp
+	ch <- c.bbuReplacementRequired
+}
+
+// WithCollectors returns a copy of the collector that only runs the named
+// sub-collectors. Names that are unknown or disabled in the config are rejected.
+func (c *MegaRAIDCollector) WithCollectors(names []string) (*MegaRAIDCollector, error) {
+	enabled := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := c.enabled[name]; !ok {
+			return nil, fmt.Errorf("unknown collector: %s", name)
+		}
+		if !c.enabled[name] {
+			return nil, fmt.Errorf("disabled collector: %s", name)
+		}
+		enabled[name] = true
+	}
+
+	filtered := *c
+	filtered.enabled = enabled
+	filtered.partial = c.partial || len(filtered.Sections()) < len(c.Sections())
+	return &filtered, nil
+}
+
+// WithBackend returns a copy of the collector that collects through b,
+// e.g. storcli on a remote host. The copy does not export tracked states,
+// which belong to the local controllers.
+func (c *MegaRAIDCollector) WithBackend(b backend.Backend) *MegaRAIDCollector {
+	remote := *c
+	remote.backend = b
+	remote.poller = nil
+	remote.history = nil
+	remote.risk = nil
+	return &remote
+}
+
+// WithRisk returns a copy of the collector that also exports the failure
+// risk scores computed by r
+func (c *MegaRAIDCollector) WithRisk(r *risk.Scorer) *MegaRAIDCollector {
+	scored := *c
+	scored.risk = r
+	return &scored
+}
+
+// WithThresholds returns a copy of the collector that also exports how many
+// values exceed the limits of e
+func (c *MegaRAIDCollector) WithThresholds(e *threshold.Evaluator) *MegaRAIDCollector {
+	checked := *c
+	checked.thresholds = e
+	return &checked
+}
+
+// WithHistory returns a copy of the collector that also exports the states
+// tracked by h across background collections
+func (c *MegaRAIDCollector) WithHistory(h *history.Tracker) *MegaRAIDCollector {
+	tracked := *c
+	tracked.history = h
+	return &tracked
+}
+
+// WithPoller returns a copy of the collector that exports the last snapshot
+// collected by p instead of running the CLI tool on every scrape
+func (c *MegaRAIDCollector) WithPoller(p *poller.Poller) *MegaRAIDCollector {
+	polled := *c
+	polled.poller = p
+	return &polled
+}
+
+// Sections returns the enabled sub-collectors as backend sections
+func (c *MegaRAIDCollector) Sections() []string {
+	var sections []string
+	for _, name := range collectorNames {
+		if c.enabled[name] {
+			sections = append(sections, name)
+		}
+	}
+	return sections
+}
+
+func (c *MegaRAIDCollector) Collect(ch chan<- prometheus.Metric) {
+	sections := c.Sections()
+	if len(sections) == 0 {
+		return
+	}
+
+	var snapshot *diskutil.Snapshot
+	if c.poller != nil {
+		// The poller logs its own failures
+		if snapshot = c.poller.Snapshot(); snapshot == nil {
+			for _, name := range sections {
+				c.collectScrapeSuccess(ch, name, false)
+			}
+			return
+		}
+	} else {
+		snapshot = c.backend.Collect(sections)
+		for _, name := range sections {
+			if msg, failed := snapshot.Errors[name]; failed {
+				log.Printf("Error collecting %s info: %s", name, msg)
+			}
+		}
+	}
+	for _, name := range sections {
+		c.collectScrapeSuccess(ch, name, snapshot.Errors[name] == "")
+	}
+
+	// A polled snapshot holds every section the poller collects
+	if c.enabled[collectorController] {
+		for _, ctrl := range snapshot.Controllers {
+			c.collectControllerMetrics(ch, ctrl)
+		}
+	}
+	if c.enabled[collectorVD] {
+		for _, vd := range snapshot.VirtualDrives {
+			c.collectVDMetrics(ch, vd)
+		}
+	}
+	if c.enabled[collectorPD] {
+		for _, pd := range snapshot.PhysicalDrives {
+			if !c.skipDrive(pd.FirmwareState) {
+				c.collectPDMetrics(ch, pd)
+			}
+		}
+	}
+	if c.enabled[collectorBBU] {
+		for _, bbu := range snapshot.Batteries {
+			c.collectBBUMetrics(ch, bbu)
+		}
+	}
+	// Thresholds count controllers, drives and batteries together. A live
+	// collection of some sections would count the others as fine and resolve
+	// alerts that still fire, so only a full snapshot is evaluated.
+	if c.thresholds != nil && (c.poller != nil || !c.partial) {
+		c.collectThresholdMetrics(ch, snapshot)
+	}
+	if c.history != nil {
+		c.collectHistoryMetrics(ch)
+	}
+	if c.risk != nil && c.enabled[collectorPD] {
+		for _, score := range c.risk.Scores() {
+			if c.skipDrive(score.State) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.pdFailureRisk,
+				prometheus.GaugeValue,
+				score.Score,
+				strconv.Itoa(score.Controller), score.Slot,
+			)
+		}
+	}
+}
+
+// collectThresholdMetrics exports both severities, so that alerts on the
+// series also resolve. A critical value only counts as critical.
+func (c *MegaRAIDCollector) collectThresholdMetrics(ch chan<- prometheus.Metric, snapshot *diskutil.Snapshot) {
+	counts := make(map[diskutil.Severity]int)
+	for _, r := range c.thresholds.Evaluate(snapshot) {
+		counts[r.Severity]++
+	}
+	for _, severity := range []diskutil.Severity{diskutil.SeverityWarning, diskutil.SeverityCritical} {
+		ch <- prometheus.MustNewConstMetric(
+			c.thresholdExceeded,
+			prometheus.GaugeValue,
+			float64(counts[severity]),
+			severity.String(),
+		)
+	}
+}
+
+func (c *MegaRAIDCollector) collectHistoryMetrics(ch chan<- prometheus.Metric) {
+	if c.enabled[collectorVD] {
+		for _, t := range c.history.Transitions() {
+			ch <- prometheus.MustNewConstMetric(
+				c.vdStateTransitions,
+				prometheus.CounterValue,
+				float64(t.Count),
+				strconv.Itoa(t.Controller), strconv.Itoa(t.VD), string(t.From), string(t.To),
+			)
+		}
+	}
+	if c.enabled[collectorPD] {
+		for _, d := range c.history.Drives() {
+			if c.skipDrive(string(d.State)) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.pdStateLastChange,
+				prometheus.GaugeValue,
+				float64(d.LastChange.UnixNano())/1e9,
+				strconv.Itoa(d.Controller), d.Slot,
+			)
+		}
+		for _, o := range c.history.Slots() {
+			if c.skipDrive(string(o.State)) {
+				continue
+			}
+			ctlStr := strconv.Itoa(o.Controller)
+			ch <- prometheus.MustNewConstMetric(
+				c.pdReplaced,
+				prometheus.CounterValue,
+				float64(o.Replacements),
+				ctlStr, o.Slot,
+			)
+			if o.Present {
+				ch <- prometheus.MustNewConstMetric(
+					c.pdSlotAge,
+					prometheus.GaugeValue,
+					time.Since(o.FirstSeen).Seconds(),
+					ctlStr, o.Slot,
+				)
+			}
+		}
+	}
+}
+
+func (c *MegaRAIDCollector) collectScrapeSuccess(ch chan<- prometheus.Metric, name string, ok bool) {
+	success := 0.0
+	if ok {
+		success = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(c.scrapeSuccess, prometheus.GaugeValue, success, name)
+}
+
+// skipDrive reports whether drives in this state are excluded by advanced.skip_drive_states
+func (c *MegaRAIDCollector) skipDrive(state string) bool {
+	if len(c.skipStates) == 0 {
+		return false
+	}
+	return c.skipStates[strings.ToLower(state)] || c.skipStates[string(diskutil.ParsePDState(state))]
+}
+
+func (c *MegaRAIDCollector) collectControllerMetrics(ch chan<- prometheus.Metric, ctrl *diskutil.ControllerStat) {
+	ctlStr := strconv.Itoa(ctrl.AdapterIndex)
+	
+	// Controller info
+	ch <- prometheus.MustNewConstMetric(
+		c.controllerInfo,
+		prometheus.GaugeValue,
+		1,
+		ctlStr, ctrl.ProductName, ctrl.SerialNumber,
+	)
+
+	// Controller status, MegaCLI does not report one
+	if ctrl.ControllerStatus != "" {
+		status := 0.0
+		if strings.ToLower(ctrl.ControllerStatus) == "optimal" {
+			status = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.controllerStatus,
+			prometheus.GaugeValue,
+			status,
+			ctlStr,
+		)
+	}
+
+	// Controller temperature
+	if ctrl.ROCTemperature > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.controllerTemp,
+			prometheus.GaugeValue,
+			float64(ctrl.ROCTemperature),
+			ctlStr,
+		)
+	}
+}
+
+func (c *MegaRAIDCollector) collectVDMetrics(ch chan<- prometheus.Metric, vd *diskutil.VirtualDriveStat) {
+	ctlStr := strconv.Itoa(vd.AdapterIndex)
+	vdStr := strconv.Itoa(vd.TargetId)
+
+	// VD info
+	ch <- prometheus.MustNewConstMetric(
+		c.vdInfo,
+		prometheus.GaugeValue,
+		1,
+		ctlStr, vdStr, vd.Name, vd.RAID_Level, vd.AccessPolicy, vd.CurrentCachePolicy,
+	)
+
+	// VD status
+	vdState := vd.NormalizedState()
+	status := 0.0
+	if vdState == diskutil.VDStateOptimal {
+		status = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.vdStatus,
+		prometheus.GaugeValue,
+		status,
+		ctlStr, vdStr,
+	)
+
+	// VD state, one-hot across all known states
+	for _, state := range diskutil.VDStates {
+		value := 0.0
+		if state == vdState {
+			value = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.vdState,
+			prometheus.GaugeValue,
+			value,
+			ctlStr, vdStr, string(state),
+		)
+	}
+
+	// VD size
+	if size := diskutil.ParseSize(vd.Size); size > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.vdSize,
+			prometheus.GaugeValue,
+			size,
+			ctlStr, vdStr,
+		)
+	}
+}
+
+func (c *MegaRAIDCollector) collectPDMetrics(ch chan<- prometheus.Metric, pd *diskutil.PhysicalDriveStat) {
+	ctlStr := strconv.Itoa(pd.AdapterIndex)
+	slot := pd.Slot()
+
+	// PD info
+	ch <- prometheus.MustNewConstMetric(
+		c.pdInfo,
+		prometheus.GaugeValue,
+		1,
+		ctlStr, slot, strconv.Itoa(pd.DeviceId), pd.Model, pd.SerialNumber, pd.WWN, pd.Pdtype, pd.MediaType, pd.RawSize,
+	)
+
+	// PD status
+	pdState := pd.NormalizedState()
+	status := 0.0
+	if pdState == diskutil.PDStateOnline {
+		status = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.pdStatus,
+		prometheus.GaugeValue,
+		status,
+		ctlStr, slot,
+	)
+
+	// PD state, one-hot across all known states
+	for _, state := range diskutil.PDStates {
+		value := 0.0
+		if state == pdState {
+			value = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.pdState,
+			prometheus.GaugeValue,
+			value,
+			ctlStr, slot, string(state),
+		)
+	}
+
+	// PD temperature
+	if temp := diskutil.ParseTemperature(pd.DriveTemperature); temp > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.pdTemp,
+			prometheus.GaugeValue,
+			temp,
+			ctlStr, slot,
+		)
+	}
+
+	// Error counts belong to the drive, not the slot
+	drive := pd.Identity()
+	ch <- prometheus.MustNewConstMetric(
+		c.pdMediaErrors,
+		prometheus.CounterValue,
+		float64(pd.MediaErrorCount),
+		ctlStr, slot, drive,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.pdOtherErrors,
+		prometheus.CounterValue,
+		float64(pd.OtherErrorCount),
+		ctlStr, slot, drive,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.pdPredictiveFailures,
+		prometheus.CounterValue,
+		float64(pd.PredictiveFailureCount),
+		ctlStr, slot, drive,
+	)
+}
+
+func (c *MegaRAIDCollector) collectBBUMetrics(ch chan<- prometheus.Metric, bbu *diskutil.BatteryBackupStat) {
+	ctlStr := strconv.Itoa(bbu.AdapterIndex)
+	bbuType := bbu.Kind()
+
+	ch <- prometheus.MustNewConstMetric(
+		c.bbuInfo,
+		prometheus.GaugeValue,
+		1,
+		ctlStr, bbuType, bbu.BatteryType, bbu.ManufactureDate,
+	)
+
+	status := 0.0
+	if strings.ToLower(bbu.BatteryState) == "optimal" {
+		status = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.bbuStatus,
+		prometheus.GaugeValue,
+		status,
+		ctlStr, bbuType,
+	)
+
+	if bbu.Temperature > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.bbuTemp,
+			prometheus.GaugeValue,
+			float64(bbu.Temperature),
+			ctlStr, bbuType,
+		)
+	}
+
+	// Only MegaCLI reports this
+	if bbu.ReplacementRequired != "" {
+		replace := 0.0
+		if strings.EqualFold(bbu.ReplacementRequired, "yes") {
+			replace = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.bbuReplacementRequired,
+			prometheus.GaugeValue,
+			replace,
+			ctlStr, bbuType,
+		)
+	}
+}
+
+// MetricNames returns the names of the metrics the collector describes
+func (c *MegaRAIDCollector) MetricNames() []string {
+	return append([]string(nil), c.names...)
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+// fakeBackend returns a hot drive and a battery, each only when its
+// section is collected
+type fakeBackend struct{}
+
+func (fakeBackend) Name() string             { return "fake" }
+func (fakeBackend) Path() string             { return "/bin/true" }
+func (fakeBackend) Version() (string, error) { return "fake 1.0", nil }
+func (fakeBackend) Collect(sections []string) *diskutil.Snapshot {
+	snapshot := diskutil.NewSnapshot("fake")
+	for _, section := range sections {
+		switch section {
+		case backend.SectionPD:
+			snapshot.PhysicalDrives = append(snapshot.PhysicalDrives, &diskutil.PhysicalDriveStat{
+				EnclosureDeviceId: 32, FirmwareState: "Onln", SerialNumber: "A", DriveTemperature: "55C"})
+		case backend.SectionBBU:
+			snapshot.Batteries = append(snapshot.Batteries, &diskutil.BatteryBackupStat{
+				BatteryType: "CVPM02", BatteryState: "Optimal", Temperature: 30})
+		}
+	}
+	return snapshot
+}
+
+func TestThresholdMetricsNeedAFullSnapshot(t *testing.T) {
+	thresholds, err := threshold.New(threshold.Config{Limits: map[string]threshold.Limit{
+		threshold.DriveTemperature: {Warning: 50},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	megaraid := NewMegaRAIDCollector(fakeBackend{}, config.NewConfig()).WithThresholds(thresholds)
+	background, err := poller.New(fakeBackend{}, megaraid.Sections(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	background.Collect()
+	filter, err := metricfilter.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		polled   bool
+		collect  []string
+		warnings float64
+		exported bool
+	}{
+		{name: "live scrape of everything", warnings: 1, exported: true},
+		{name: "live scrape of the drives", collect: []string{"controller", "vd", "pd"}, exported: false},
+		{name: "live scrape of the batteries", collect: []string{"bbu"}, exported: false},
+		{name: "polled scrape of everything", polled: true, warnings: 1, exported: true},
+		{name: "polled scrape of the batteries", polled: true, collect: []string{"bbu"}, warnings: 1, exported: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := megaraid
+			if tt.polled {
+				collector = megaraid.WithPoller(background)
+			}
+			gatherer, err := NewHandler(collector, nil, nil, filter).Gatherer(tt.collect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			families, err := gatherer.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exported := false
+			for _, mf := range families {
+				if mf.GetName() != "megaraid_threshold_exceeded" {
+					continue
+				}
+				exported = true
+				for _, m := range mf.GetMetric() {
+					if m.GetLabel()[0].GetValue() == "warning" && m.GetGauge().GetValue() != tt.warnings {
+						t.Errorf("got %v warnings, want %v", m.GetGauge().GetValue(), tt.warnings)
+					}
+				}
+			}
+			if exported != tt.exported {
+				t.Errorf("threshold metrics exported: %v, want %v", exported, tt.exported)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+)
+
+// collectorKernel selects the kernel log collector in collect[]
+const collectorKernel = "kernel"
+
+// Handler serves the exporter metrics. It builds a fresh registry for every
+// scrape so that collect[] can pick sub-collectors, in the style of node_exporter:
+//
+//	/metrics?collect[]=pd&collect[]=bbu
+type Handler struct {
+	collector *MegaRAIDCollector
+	kernel    prometheus.Collector // nil when the kernel log watcher is disabled
+	labels    prometheus.Labels
+	filter    *metricfilter.Filter
+}
+
+// NewHandler returns a metrics handler. kernel may be nil when the kernel log
+// watcher is disabled; labels are attached to every exporter metric.
+func NewHandler(collector *MegaRAIDCollector, kernel prometheus.Collector, labels prometheus.Labels, filter *metricfilter.Filter) *Handler {
+	return &Handler{
+		collector: collector,
+		kernel:    kernel,
+		labels:    labels,
+		filter:    filter,
+	}
+}
+
+// Gatherer returns a gatherer for the named sub-collectors, or for every
+// enabled one when names is empty. It does not include process metrics.
+func (h *Handler) Gatherer(names []string) (prometheus.Gatherer, error) {
+	collector := h.collector
+	kernel := h.kernel
+
+	if len(names) > 0 {
+		var storcliNames []string
+		wantKernel := false
+		for _, name := range names {
+			if name == collectorKernel {
+				if h.kernel == nil {
+					return nil, fmt.Errorf("disabled collector: %s", name)
+				}
+				wantKernel = true
+				continue
+			}
+			storcliNames = append(storcliNames, name)
+		}
+
+		var err error
+		if collector, err = h.collector.WithCollectors(storcliNames); err != nil {
+			return nil, err
+		}
+		if !wantKernel {
+			kernel = nil
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	registerer := prometheus.WrapRegistererWith(h.labels, registry)
+	if err := registerer.Register(collector); err != nil {
+		return nil, err
+	}
+	if kernel != nil {
+		if err := registerer.Register(kernel); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.filter.Gatherer(registry), nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gatherer, err := h.Gatherer(r.URL.Query()["collect[]"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gatherers := prometheus.Gatherers{h.filter.Gatherer(prometheus.DefaultGatherer), gatherer}
+	promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorLog: log.Default(),
+	}).ServeHTTP(w, r)
+}
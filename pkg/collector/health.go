@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+)
+
+// HealthHandler serves the liveness and readiness endpoints:
+//
+//	/-/healthy  the process is up
+//	/-/ready    the CLI tool is installed and the last background collection
+//	            succeeded and is recent
+type HealthHandler struct {
+	poller   *poller.Poller
+	toolPath string
+	maxAge   time.Duration
+}
+
+// NewHealthHandler returns a handler that reports ready while the last
+// collection by p is no older than readyIntervals collection intervals
+func NewHealthHandler(p *poller.Poller, toolPath string, readyIntervals int) *HealthHandler {
+	if readyIntervals < 1 {
+		readyIntervals = 1
+	}
+	return &HealthHandler{
+		poller:   p,
+		toolPath: toolPath,
+		maxAge:   time.Duration(readyIntervals) * p.Interval(),
+	}
+}
+
+type readiness struct {
+	Status         string                            `json:"status"`
+	Tool           toolStatus                        `json:"tool"`
+	LastCollection *time.Time                        `json:"last_collection,omitempty"`
+	AgeSeconds     float64                           `json:"age_seconds"`
+	MaxAgeSeconds  float64                           `json:"max_age_seconds"`
+	Collectors     map[string]poller.CollectorStatus `json:"collectors"`
+	Reasons        []string                          `json:"reasons,omitempty"`
+}
+
+type toolStatus struct {
+	Path  string `json:"path"`
+	Found bool   `json:"found"`
+	Error string `json:"error,omitempty"`
+}
+
+// Healthy reports that the process is alive
+func (h *HealthHandler) Healthy(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+}
+
+// Ready reports whether the exporter has fresh, successful data
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	status := h.poller.Status()
+	body := readiness{
+		Status:        "ready",
+		Tool:          toolStatus{Path: h.toolPath, Found: true},
+		MaxAgeSeconds: h.maxAge.Seconds(),
+		Collectors:    status.Collectors,
+	}
+	if body.Collectors == nil {
+		body.Collectors = map[string]poller.CollectorStatus{}
+	}
+
+	if err := backend.CheckTool(h.toolPath); err != nil {
+		body.Tool.Found = false
+		body.Tool.Error = err.Error()
+		body.Reasons = append(body.Reasons, "CLI tool not found")
+	}
+
+	if status.LastCollection.IsZero() {
+		body.Reasons = append(body.Reasons, "no collection yet")
+	} else {
+		body.LastCollection = &status.LastCollection
+		age := time.Since(status.LastCollection)
+		body.AgeSeconds = age.Seconds()
+		if !status.Succeeded() {
+			body.Reasons = append(body.Reasons, "last collection failed")
+		}
+		if age > h.maxAge {
+			body.Reasons = append(body.Reasons, "last collection is stale")
+		}
+	}
+
+	code := http.StatusOK
+	if len(body.Reasons) > 0 {
+		body.Status = "not_ready"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
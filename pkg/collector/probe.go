@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+	"github.com/yourusername/megaraid-exporter/pkg/runner"
+)
+
+// ProbeHandler collects from a remote host over SSH, in the style of
+// blackbox_exporter:
+//
+//	/probe?target=storage01.example.com
+type ProbeHandler struct {
+	collector *MegaRAIDCollector
+	probe     config.ProbeConfig
+	labels    prometheus.Labels
+	filter    *metricfilter.Filter
+}
+
+func NewProbeHandler(collector *MegaRAIDCollector, probe config.ProbeConfig, labels prometheus.Labels, filter *metricfilter.Filter) *ProbeHandler {
+	return &ProbeHandler{
+		collector: collector,
+		probe:     probe,
+		labels:    labels,
+		filter:    filter,
+	}
+}
+
+func (h *ProbeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Whether the probe succeeded (1=success, 0=failure)",
+	})
+	probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "Duration of the probe in seconds",
+	})
+	probeRegistry := prometheus.NewRegistry()
+	probeRegistry.MustRegister(probeSuccess, probeDuration)
+
+	registry := prometheus.NewRegistry()
+	connected := false
+	conn, err := runner.DialSSH(h.probe.SSH, target)
+	if err == nil {
+		defer conn.Close()
+		var remote backend.Backend
+		if remote, err = h.remoteBackend(conn); err == nil {
+			registerer := prometheus.WrapRegistererWith(h.labels, registry)
+			registerer.MustRegister(h.collector.WithBackend(remote))
+			connected = true
+		}
+	}
+	if err != nil {
+		log.Printf("Probe of %s failed: %v", target, err)
+	}
+
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := registry.Gather()
+		if connected && err == nil && ScrapeSucceeded(families) {
+			probeSuccess.Set(1)
+		}
+		families = h.filter.Apply(families)
+		probeDuration.Set(time.Since(start).Seconds())
+
+		probeFamilies, probeErr := probeRegistry.Gather()
+		if err == nil {
+			err = probeErr
+		}
+		return append(families, probeFamilies...), err
+	})
+
+	promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		ErrorLog: log.Default(),
+	}).ServeHTTP(w, r)
+}
+
+// remoteBackend returns the configured backend on the target. "auto" asks the
+// target for the storcli version, as the local binaries say nothing about
+// what is installed there.
+func (h *ProbeHandler) remoteBackend(r runner.Runner) (backend.Backend, error) {
+	name := h.probe.Backend
+	if name == backend.NameAuto || name == "" {
+		name = backend.NameStorCLI
+		if _, err := backend.NewStorCLI(r, h.probe.StorCLIPath, nil).Version(); err != nil && h.probe.MegaCLIPath != "" {
+			name = backend.NameMegaCLI
+		}
+	}
+	return backend.New(name, r, h.probe.StorCLIPath, h.probe.MegaCLIPath, nil)
+}
+
+// ScrapeSucceeded reports whether every sub-collector in families reported success
+func ScrapeSucceeded(families []*dto.MetricFamily) bool {
+	for _, mf := range families {
+		if !strings.HasSuffix(mf.GetName(), "scrape_collector_success") {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() != 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+package collector
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/pkg/metricfilter"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const probeControllerJSON = `{"Controllers":[{"Command Status":{"Controller":0,"Status":"Success"},"Response Data":{
+"Basics":{"Controller":0,"Model":"PERC H730","Serial Number":"SN0"},
+"Version":{"Firmware Package Build":"25.5.5.0005","Firmware Version":"4.300.00-8366"},
+"Status":{"Controller Status":"Optimal"},
+"HwCfg":{"ROC temperature(Degree Celsius)":55},
+"VD LIST":[{"DG/VD":"0/0","TYPE":"RAID1","State":"Optl","Access":"RW","Cache":"RWBD","Size":"1.0 TB","Name":"os"}],
+"PD LIST":[{"EID:Slt":"32:0","DID":0,"State":"Onln","DG":0,"Size":"1.0 TB","Intf":"SAS","Med":"HDD","SED":"N","PI":"N","SeSz":"512B","Model":"ST1000NM0023","Sp":"U","Type":"-"}]}}]}`
+
+const probeDrivesJSON = `{"Controllers":[{"Command Status":{"Controller":0,"Status":"Success"},"Response Data":{
+"Drive /c0/e32/s0":[{"EID:Slt":"32:0","DID":0,"State":"Onln","DG":0,"Size":"1.0 TB","Intf":"SAS","Med":"HDD","SED":"N","PI":"N","SeSz":"512B","Model":"ST1000NM0023","Sp":"U","Type":"-"}],
+"Drive /c0/e32/s0 - Detailed Information":{
+"Drive /c0/e32/s0 State":{"Shield Counter":0,"Media Error Count":2,"Other Error Count":0,"Drive Temperature":" 35C (95.00 F)","Predictive Failure Count":0,"S.M.A.R.T alert flagged by drive":"No"},
+"Drive /c0/e32/s0 Device attributes":{"SN":"        Z1Z0AAAA","WWN":"5000C500AAAA0000","Firmware Revision":"0004    "}}}}]}`
+
+// storcliOverSSH answers storcli commands for one controller with a virtual
+// drive, a drive with media errors and a CacheVault but no BBU. fail makes every command exit
+// with status 1.
+func storcliOverSSH(fail bool) func(args []string) (string, uint32) {
+	return func(args []string) (string, uint32) {
+		if fail || len(args) < 2 {
+			return "storcli: controller not found\n", 1
+		}
+		switch {
+		case args[1] == "-v":
+			return "StorCli SAS Customization Utility Ver 007.1017.0000.0000 May 10, 2019\n", 0
+		case strings.HasSuffix(args[1], "/bbu"):
+			return `{"Controllers":[{"Command Status":{"Controller":0,"Status":"Failure"},"Response Data":{}}]}`, 1
+		case strings.HasSuffix(args[1], "/cv"):
+			return `{"Controllers":[{"Command Status":{"Controller":0,"Status":"Success"},"Response Data":{"Cachevault_Info":[{"Model":"CVPM02","State":"Optimal","Temp":"28C","MfgDate":"2016/05/05"}]}}]}`, 0
+		case strings.HasSuffix(args[1], "/eall/sall"):
+			return probeDrivesJSON, 0
+		}
+		return probeControllerJSON, 0
+	}
+}
+
+// sshServer runs commands with run for any client key in authorized and
+// returns its address and host key
+func sshServer(t *testing.T, authorized ssh.PublicKey, run func(args []string) (string, uint32)) (string, ssh.PublicKey) {
+	t.Helper()
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, cfg, run)
+		}
+	}()
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+func serveSSH(conn net.Conn, cfg *ssh.ServerConfig, run func(args []string) (string, uint32)) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				var exec struct{ Command string }
+				ssh.Unmarshal(req.Payload, &exec)
+				req.Reply(true, nil)
+
+				// The runner quotes every word, none of them contain spaces here
+				var args []string
+				for _, word := range strings.Fields(exec.Command) {
+					args = append(args, strings.Trim(word, "'"))
+				}
+				output, status := run(args)
+				channel.Write([]byte(output))
+				channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				return
+			}
+		}()
+	}
+}
+
+func TestProbe(t *testing.T) {
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		fail        bool
+		wrongHost   bool
+		success     string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:    "success",
+			success: "probe_success 1",
+			contains: []string{
+				`megaraid_controller_info{controller="0",model="PERC H730",serial="SN0"} 1`,
+				`megaraid_pd_status{controller="0",enclosure_slot="32:0"} 1`,
+				`megaraid_pd_media_errors_total{controller="0",drive="5000C500AAAA0000",enclosure_slot="32:0"} 2`,
+				`megaraid_pd_temperature_celsius{controller="0",enclosure_slot="32:0"} 35`,
+				`megaraid_vd_status{controller="0",vd="0"} 1`,
+				`megaraid_bbu_temperature_celsius{controller="0",type="cachevault"} 28`,
+			},
+		},
+		{
+			name:        "known_hosts mismatch",
+			wrongHost:   true,
+			success:     "probe_success 0",
+			notContains: []string{"megaraid_"},
+		},
+		{
+			name:     "command failure",
+			fail:     true,
+			success:  "probe_success 0",
+			contains: []string{`megaraid_scrape_collector_success{collector="pd"} 0`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, hostKey := sshServer(t, clientSigner.PublicKey(), storcliOverSSH(tt.fail))
+			if tt.wrongHost {
+				hostKey = otherSigner.PublicKey()
+			}
+			knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+			line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostKey) + "\n"
+			if err := os.WriteFile(knownHostsFile, []byte(line), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := config.NewConfig()
+			cfg.Probe.SSH.KeyFile = keyFile
+			cfg.Probe.SSH.KnownHostsFile = knownHostsFile
+			cfg.Probe.SSH.Timeout = 5 * time.Second
+			filter, err := metricfilter.New(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := NewProbeHandler(NewMegaRAIDCollector(nil, cfg), cfg.Probe, nil, filter)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/probe?target="+addr, nil))
+			body := rec.Body.String()
+
+			if !strings.Contains(body, tt.success+"\n") {
+				t.Errorf("expected %q in:\n%s", tt.success, body)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected %q in:\n%s", want, body)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("unexpected %q in:\n%s", unwanted, body)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeMissingTarget(t *testing.T) {
+	cfg := config.NewConfig()
+	handler := NewProbeHandler(NewMegaRAIDCollector(nil, cfg), cfg.Probe, nil, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/probe", nil))
+	if rec.Code != 400 {
+		t.Errorf("got status %d, want 400", rec.Code)
+	}
+}
+
+// toolRunner pretends that only the tools it maps to true are installed
+type toolRunner map[string]bool
+
+func (r toolRunner) Run(path string, args ...string) ([]byte, error) {
+	if !r[path] {
+		return nil, os.ErrNotExist
+	}
+	return []byte("version 1\n"), nil
+}
+
+func TestRemoteBackend(t *testing.T) {
+	storcli, megacli := "/opt/MegaRAID/storcli/storcli64", "/opt/MegaRAID/MegaCli/MegaCli64"
+	tests := []struct {
+		backend   string
+		megacli   string
+		installed toolRunner
+		want      string
+		err       bool
+	}{
+		{"storcli", megacli, toolRunner{megacli: true}, "storcli", false},
+		{"megacli", megacli, toolRunner{storcli: true}, "megacli", false},
+		{"megacli", "", toolRunner{}, "", true},
+		{"auto", megacli, toolRunner{storcli: true, megacli: true}, "storcli", false},
+		{"auto", megacli, toolRunner{megacli: true}, "megacli", false},
+		{"auto", "", toolRunner{}, "storcli", false},
+		{"", megacli, toolRunner{megacli: true}, "megacli", false},
+		{"smartctl", megacli, toolRunner{}, "", true},
+	}
+	for _, tt := range tests {
+		h := &ProbeHandler{probe: config.ProbeConfig{Backend: tt.backend, StorCLIPath: storcli, MegaCLIPath: tt.megacli}}
+		b, err := h.remoteBackend(tt.installed)
+		if (err != nil) != tt.err {
+			t.Errorf("%q with %v: got error %v, want error %v", tt.backend, tt.installed, err, tt.err)
+			continue
+		}
+		if err == nil && b.Name() != tt.want {
+			t.Errorf("%q with %v: got %s, want %s", tt.backend, tt.installed, b.Name(), tt.want)
+		}
+	}
+}
@@ -34,6 +34,12 @@ func (b *BatteryBackupStat) parseLine(line string) error {
 			return err
 		}
 		b.BatteryType = batteryType.(string)
+	} else if strings.HasPrefix(line, keyBbuBatteryTypeCompact) {
+		batteryType, err := parseFiled(line, keyBbuBatteryTypeCompact, typeString)
+		if err != nil {
+			return err
+		}
+		b.BatteryType = batteryType.(string)
 	} else if strings.HasPrefix(line, keyBbuBatteryState) {
 		batteryState, err := parseFiled(line, keyBbuBatteryState, typeString)
 		if err != nil {
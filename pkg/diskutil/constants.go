@@ -9,9 +9,12 @@ const (
 	keyPdOtherErrorCount          = "Other Error Count:"
 	keyPdPredictiveFailureCount   = "Predictive Failure Count:"
 	keyPdPdtype                   = "PD Type:"
+	keyPdMediaType                = "Media Type:"
 	keyPdRawSize                  = "Raw Size:"
 	keyPdFirmwareState            = "Firmware state:"
 	keyPdInquiryData              = "Inquiry Data:"
+	keyPdWWN                      = "WWN:"
+	keyPdFirmwareLevel            = "Device Firmware Level:"
 	keyPdDriveTemperature         = "Drive Temperature:"
 	keyPdSMARTFlag                = "SMART Flag:"
 	keyPdSMARTAlertFlagged        = "SMART alert flagged by drive:"
@@ -20,7 +23,9 @@ const (
 
 // Battery Backup Unit parsing keys
 const (
+	keyBbuStatusForAdapter        = "BBU status for Adapter:"
 	keyBbuBatteryType             = "Battery Type:"
+	keyBbuBatteryTypeCompact      = "BatteryType:"
 	keyBbuBatteryState            = "Battery State:"
 	keyBbuChargeStatus            = "Charge Status:"
 	keyBbuAbsoluteStateOfCharge   = "Absolute State of charge:"
@@ -42,6 +47,7 @@ const (
 
 // Controller parsing keys
 const (
+	keyCtrlAdapter                = "Adapter #"
 	keyCtrlProductName            = "Product Name:"
 	keyCtrlSerialNumber           = "Serial No:"
 	keyCtrlFWVersion              = "FW Version:"
@@ -62,6 +68,7 @@ const (
 
 // Virtual Drive parsing keys
 const (
+	keyVdVirtualDrive             = "Virtual Drive:"
 	keyVdTargetId                 = "Target Id:"
 	keyVdName                     = "Name:"
 	keyVdRAIDLevel                = "RAID Level:"
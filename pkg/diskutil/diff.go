@@ -0,0 +1,264 @@
+package diskutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of change reported by Diff
+const (
+	ChangeControllerAdded   = "controller_added"
+	ChangeControllerRemoved = "controller_removed"
+	ChangeControllerState   = "controller_state"
+	ChangeFirmware          = "firmware"
+	ChangeVDAdded           = "vd_added"
+	ChangeVDRemoved         = "vd_removed"
+	ChangeVDState           = "vd_state"
+	ChangeCachePolicy       = "cache_policy"
+	ChangeDriveAdded        = "drive_added"
+	ChangeDriveRemoved      = "drive_removed"
+	ChangeDriveReplaced     = "drive_replaced"
+	ChangePDState           = "pd_state"
+	ChangeMediaErrors       = "media_errors"
+	ChangeOtherErrors       = "other_errors"
+	ChangePredictiveFailure = "predictive_failures"
+	ChangeBBUState          = "bbu_state"
+)
+
+// Change is one difference between two snapshots
+type Change struct {
+	Kind      string   `json:"kind"`
+	Severity  Severity `json:"severity"`
+	Component string   `json:"component"`
+	Message   string   `json:"message"`
+	Old       string   `json:"old,omitempty"`
+	New       string   `json:"new,omitempty"`
+}
+
+// Diff reports what changed from before to after. Drives are matched by slot and
+// a different serial number in the same slot is reported as a replacement.
+func Diff(before, after *Snapshot) []Change {
+	var changes []Change
+	changes = append(changes, diffControllers(before.Controllers, after.Controllers)...)
+	changes = append(changes, diffVirtualDrives(before.VirtualDrives, after.VirtualDrives)...)
+	changes = append(changes, diffPhysicalDrives(before.PhysicalDrives, after.PhysicalDrives)...)
+	changes = append(changes, diffBatteries(before.Batteries, after.Batteries)...)
+	return changes
+}
+
+func controllerComponent(c *ControllerStat) string {
+	return fmt.Sprintf("controller %d", c.AdapterIndex)
+}
+
+func vdComponent(v *VirtualDriveStat) string {
+	return fmt.Sprintf("vd %d/%d", v.AdapterIndex, v.TargetId)
+}
+
+func pdComponent(p *PhysicalDriveStat) string {
+	return fmt.Sprintf("pd %d/%s", p.AdapterIndex, p.Slot())
+}
+
+func bbuComponent(b *BatteryBackupStat) string {
+	return fmt.Sprintf("%s %d", b.Kind(), b.AdapterIndex)
+}
+
+// changed returns a change when a field differs, ignoring fields the
+// backend did not report in one of the snapshots
+func changed(kind string, severity Severity, component, field, before, after string) []Change {
+	if before == after || before == "" || after == "" {
+		return nil
+	}
+	return []Change{{
+		Kind:      kind,
+		Severity:  severity,
+		Component: component,
+		Message:   fmt.Sprintf("%s changed from %s to %s", field, before, after),
+		Old:       before,
+		New:       after,
+	}}
+}
+
+// matchKeys returns the union of keys in both maps, sorted
+func matchKeys[T any](before, after map[string]T) []string {
+	seen := make(map[string]bool)
+	for key := range before {
+		seen[key] = true
+	}
+	for key := range after {
+		seen[key] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffControllers(oldList, newList []*ControllerStat) []Change {
+	before, after := make(map[string]*ControllerStat), make(map[string]*ControllerStat)
+	for _, c := range oldList {
+		before[controllerComponent(c)] = c
+	}
+	for _, c := range newList {
+		after[controllerComponent(c)] = c
+	}
+
+	var changes []Change
+	for _, key := range matchKeys(before, after) {
+		o, n := before[key], after[key]
+		switch {
+		case o == nil:
+			changes = append(changes, Change{Kind: ChangeControllerAdded, Severity: SeverityOK, Component: key,
+				Message: fmt.Sprintf("controller added: %s", n.ProductName), New: n.ProductName})
+		case n == nil:
+			changes = append(changes, Change{Kind: ChangeControllerRemoved, Severity: SeverityCritical, Component: key,
+				Message: fmt.Sprintf("controller removed: %s", o.ProductName), Old: o.ProductName})
+		default:
+			severity := SeverityOK
+			if !strings.EqualFold(n.ControllerStatus, "optimal") {
+				severity = SeverityCritical
+			}
+			changes = append(changes, changed(ChangeControllerState, severity, key, "status", o.ControllerStatus, n.ControllerStatus)...)
+			changes = append(changes, changed(ChangeFirmware, SeverityOK, key, "firmware", o.FWVersion, n.FWVersion)...)
+		}
+	}
+	return changes
+}
+
+func diffVirtualDrives(oldList, newList []*VirtualDriveStat) []Change {
+	before, after := make(map[string]*VirtualDriveStat), make(map[string]*VirtualDriveStat)
+	for _, v := range oldList {
+		before[vdComponent(v)] = v
+	}
+	for _, v := range newList {
+		after[vdComponent(v)] = v
+	}
+
+	var changes []Change
+	for _, key := range matchKeys(before, after) {
+		o, n := before[key], after[key]
+		switch {
+		case o == nil:
+			changes = append(changes, Change{Kind: ChangeVDAdded, Severity: SeverityOK, Component: key,
+				Message: fmt.Sprintf("virtual drive added: %s %s", n.RAID_Level, n.Size), New: n.RAID_Level})
+		case n == nil:
+			changes = append(changes, Change{Kind: ChangeVDRemoved, Severity: SeverityWarning, Component: key,
+				Message: fmt.Sprintf("virtual drive removed: %s %s", o.RAID_Level, o.Size), Old: o.RAID_Level})
+		default:
+			newState := n.NormalizedState()
+			changes = append(changes, changed(ChangeVDState, newState.Severity(), key, "state",
+				string(o.NormalizedState()), string(newState))...)
+			changes = append(changes, changed(ChangeCachePolicy, SeverityWarning, key, "cache policy",
+				o.CurrentCachePolicy, n.CurrentCachePolicy)...)
+			changes = append(changes, changed(ChangeCachePolicy, SeverityWarning, key, "default cache policy",
+				o.DefaultCachePolicy, n.DefaultCachePolicy)...)
+			changes = append(changes, changed(ChangeCachePolicy, SeverityWarning, key, "disk cache policy",
+				o.DiskCachePolicy, n.DiskCachePolicy)...)
+		}
+	}
+	return changes
+}
+
+func diffPhysicalDrives(oldList, newList []*PhysicalDriveStat) []Change {
+	before, after := make(map[string]*PhysicalDriveStat), make(map[string]*PhysicalDriveStat)
+	for _, p := range oldList {
+		before[pdComponent(p)] = p
+	}
+	for _, p := range newList {
+		after[pdComponent(p)] = p
+	}
+
+	var changes []Change
+	for _, key := range matchKeys(before, after) {
+		o, n := before[key], after[key]
+		switch {
+		case o == nil:
+			changes = append(changes, Change{Kind: ChangeDriveAdded, Severity: SeverityOK, Component: key,
+				Message: fmt.Sprintf("drive added: %s", describeDrive(n)), New: n.SerialNumber})
+		case n == nil:
+			changes = append(changes, Change{Kind: ChangeDriveRemoved, Severity: SeverityWarning, Component: key,
+				Message: fmt.Sprintf("drive removed: %s", describeDrive(o)), Old: o.SerialNumber})
+		case replaced(o, n):
+			changes = append(changes, Change{Kind: ChangeDriveReplaced, Severity: SeverityWarning, Component: key,
+				Message: fmt.Sprintf("drive replaced: %s -> %s", describeDrive(o), describeDrive(n)),
+				Old:     o.SerialNumber, New: n.SerialNumber})
+			// The counters and state belong to a different drive now
+			changes = append(changes, changed(ChangePDState, n.NormalizedState().Severity(), key, "state",
+				string(o.NormalizedState()), string(n.NormalizedState()))...)
+		default:
+			newState := n.NormalizedState()
+			changes = append(changes, changed(ChangePDState, newState.Severity(), key, "state",
+				string(o.NormalizedState()), string(newState))...)
+			if n.MediaErrorCount > o.MediaErrorCount {
+				changes = append(changes, Change{Kind: ChangeMediaErrors, Severity: SeverityWarning, Component: key,
+					Message: fmt.Sprintf("%d new media errors (%d total)", n.MediaErrorCount-o.MediaErrorCount, n.MediaErrorCount),
+					Old:     fmt.Sprint(o.MediaErrorCount), New: fmt.Sprint(n.MediaErrorCount)})
+			}
+			if n.OtherErrorCount > o.OtherErrorCount {
+				changes = append(changes, Change{Kind: ChangeOtherErrors, Severity: SeverityWarning, Component: key,
+					Message: fmt.Sprintf("%d new other errors (%d total)", n.OtherErrorCount-o.OtherErrorCount, n.OtherErrorCount),
+					Old:     fmt.Sprint(o.OtherErrorCount), New: fmt.Sprint(n.OtherErrorCount)})
+			}
+			if n.PredictiveFailureCount > o.PredictiveFailureCount {
+				changes = append(changes, Change{Kind: ChangePredictiveFailure, Severity: SeverityWarning, Component: key,
+					Message: fmt.Sprintf("%d new predictive failures (%d total)", n.PredictiveFailureCount-o.PredictiveFailureCount, n.PredictiveFailureCount),
+					Old:     fmt.Sprint(o.PredictiveFailureCount), New: fmt.Sprint(n.PredictiveFailureCount)})
+			}
+			changes = append(changes, changed(ChangeFirmware, SeverityOK, key, "firmware", o.FirmwareLevel, n.FirmwareLevel)...)
+		}
+	}
+	return changes
+}
+
+func diffBatteries(oldList, newList []*BatteryBackupStat) []Change {
+	before, after := make(map[string]*BatteryBackupStat), make(map[string]*BatteryBackupStat)
+	for _, b := range oldList {
+		before[bbuComponent(b)] = b
+	}
+	for _, b := range newList {
+		after[bbuComponent(b)] = b
+	}
+
+	var changes []Change
+	for _, key := range matchKeys(before, after) {
+		o, n := before[key], after[key]
+		if o == nil || n == nil {
+			continue
+		}
+		severity := SeverityOK
+		if !strings.EqualFold(n.BatteryState, "optimal") {
+			severity = SeverityWarning
+		}
+		changes = append(changes, changed(ChangeBBUState, severity, key, "state", o.BatteryState, n.BatteryState)...)
+		changes = append(changes, changed(ChangeFirmware, SeverityOK, key, "firmware", o.FirmwareVersion, n.FirmwareVersion)...)
+	}
+	return changes
+}
+
+// replaced reports whether the drive in a slot is a different one. The WWN
+// decides when both snapshots have it, then the serial number; otherwise a
+// different model is taken as a replacement.
+func replaced(before, after *PhysicalDriveStat) bool {
+	if before.WWN != "" && after.WWN != "" {
+		return before.WWN != after.WWN
+	}
+	if before.SerialNumber != "" && after.SerialNumber != "" {
+		return before.SerialNumber != after.SerialNumber
+	}
+	return before.Model != "" && after.Model != "" && before.Model != after.Model
+}
+
+func describeDrive(p *PhysicalDriveStat) string {
+	parts := []string{}
+	for _, s := range []string{p.Model, p.SerialNumber, p.RawSize} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown drive"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,113 @@
+package diskutil
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	drive := func(slot int, serial, state string, mediaErrors int) *PhysicalDriveStat {
+		return &PhysicalDriveStat{EnclosureDeviceId: 32, SlotNumber: slot, SerialNumber: serial,
+			Model: "ST1000", FirmwareState: state, MediaErrorCount: mediaErrors}
+	}
+	controller := func(status, firmware string) *ControllerStat {
+		return &ControllerStat{ProductName: "PERC H730", ControllerStatus: status, FWVersion: firmware}
+	}
+
+	tests := []struct {
+		name   string
+		before *Snapshot
+		after  *Snapshot
+		want   []Change
+	}{
+		{
+			name:   "no change",
+			before: &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(0, "A", "Onln", 0)}},
+			after:  &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(0, "A", "Online, Spun Up", 0)}},
+		},
+		{
+			name:   "controller degraded",
+			before: &Snapshot{Controllers: []*ControllerStat{controller("Optimal", "4.300.00-8366")}},
+			after:  &Snapshot{Controllers: []*ControllerStat{controller("Degraded", "4.300.00-8366")}},
+			want:   []Change{{Kind: ChangeControllerState, Severity: SeverityCritical, Component: "controller 0", Old: "Optimal", New: "Degraded"}},
+		},
+		{
+			name:   "controller recovered and firmware updated",
+			before: &Snapshot{Controllers: []*ControllerStat{controller("Degraded", "4.300.00-8366")}},
+			after:  &Snapshot{Controllers: []*ControllerStat{controller("Optimal", "4.300.00-8400")}},
+			want: []Change{
+				{Kind: ChangeControllerState, Severity: SeverityOK, Component: "controller 0", Old: "Degraded", New: "Optimal"},
+				{Kind: ChangeFirmware, Severity: SeverityOK, Component: "controller 0", Old: "4.300.00-8366", New: "4.300.00-8400"},
+			},
+		},
+		{
+			name:   "controller status not reported",
+			before: &Snapshot{Controllers: []*ControllerStat{controller("Optimal", "")}},
+			after:  &Snapshot{Controllers: []*ControllerStat{controller("", "")}},
+		},
+		{
+			name:   "controller removed",
+			before: &Snapshot{Controllers: []*ControllerStat{controller("Optimal", "")}},
+			after:  &Snapshot{},
+			want:   []Change{{Kind: ChangeControllerRemoved, Severity: SeverityCritical, Component: "controller 0", Old: "PERC H730"}},
+		},
+		{
+			name:   "drive failed with new media errors",
+			before: &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(1, "A", "Onln", 2)}},
+			after:  &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(1, "A", "Offln", 5)}},
+			want: []Change{
+				{Kind: ChangePDState, Severity: ParsePDState("Offln").Severity(), Component: "pd 0/32:1", Old: "online", New: "offline"},
+				{Kind: ChangeMediaErrors, Severity: SeverityWarning, Component: "pd 0/32:1", Old: "2", New: "5"},
+			},
+		},
+		{
+			name:   "drive replaced does not report its counters",
+			before: &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(1, "A", "Offln", 5)}},
+			after:  &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(1, "B", "Rbld", 0)}},
+			want: []Change{
+				{Kind: ChangeDriveReplaced, Severity: SeverityWarning, Component: "pd 0/32:1", Old: "A", New: "B"},
+				{Kind: ChangePDState, Severity: ParsePDState("Rbld").Severity(), Component: "pd 0/32:1", Old: "offline", New: "rebuild"},
+			},
+		},
+		{
+			name:   "drive added and removed",
+			before: &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(1, "A", "Onln", 0)}},
+			after:  &Snapshot{PhysicalDrives: []*PhysicalDriveStat{drive(2, "A", "Onln", 0)}},
+			want: []Change{
+				{Kind: ChangeDriveRemoved, Severity: SeverityWarning, Component: "pd 0/32:1", Old: "A"},
+				{Kind: ChangeDriveAdded, Severity: SeverityOK, Component: "pd 0/32:2", New: "A"},
+			},
+		},
+		{
+			name:   "virtual drive degraded",
+			before: &Snapshot{VirtualDrives: []*VirtualDriveStat{{State: "Optl", CurrentCachePolicy: "WriteBack"}}},
+			after:  &Snapshot{VirtualDrives: []*VirtualDriveStat{{State: "Dgrd", CurrentCachePolicy: "WriteThrough"}}},
+			want: []Change{
+				{Kind: ChangeVDState, Severity: VDStateDegraded.Severity(), Component: "vd 0/0", Old: "optimal", New: "degraded"},
+				{Kind: ChangeCachePolicy, Severity: SeverityWarning, Component: "vd 0/0", Old: "WriteBack", New: "WriteThrough"},
+			},
+		},
+		{
+			name:   "battery failed",
+			before: &Snapshot{Batteries: []*BatteryBackupStat{{BatteryType: "iBBU", BatteryState: "Optimal"}}},
+			after:  &Snapshot{Batteries: []*BatteryBackupStat{{BatteryType: "iBBU", BatteryState: "Failed"}}},
+			want:   []Change{{Kind: ChangeBBUState, Severity: SeverityWarning, Component: "bbu 0", Old: "Optimal", New: "Failed"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.before, tt.after)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				c := got[i]
+				if c.Kind != want.Kind || c.Severity != want.Severity || c.Component != want.Component ||
+					c.Old != want.Old || c.New != want.New {
+					t.Errorf("change %d: got %+v, want %+v", i, c, want)
+				}
+				if c.Message == "" {
+					t.Errorf("change %d has no message", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package diskutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity ranks how urgent a problem is
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "ok"
+}
+
+// ParseSeverity parses "ok", "warning" or "critical"
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "ok", "":
+		return SeverityOK, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityOK, fmt.Errorf("unknown severity: %s", s)
+}
+
+// MarshalText encodes the severity by name in JSON output
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Severity returns how urgent it is for a drive to be in this state
+func (s PDState) Severity() Severity {
+	switch s {
+	case PDStateOnline, PDStateHotspare, PDStateUnconfiguredGood, PDStateJBOD:
+		return SeverityOK
+	case PDStateFailed, PDStateOffline, PDStateMissing, PDStateUnconfiguredBad:
+		return SeverityCritical
+	}
+	return SeverityWarning
+}
+
+// Severity returns how urgent it is for a virtual drive to be in this state
+func (s VDState) Severity() Severity {
+	switch s {
+	case VDStateOptimal:
+		return SeverityOK
+	case VDStateDegraded, VDStateOffline:
+		return SeverityCritical
+	}
+	return SeverityWarning
+}
+
+// Problem is one thing in a snapshot that needs attention
+type Problem struct {
+	Severity  Severity `json:"severity"`
+	Component string   `json:"component"`
+	Message   string   `json:"message"`
+}
+
+// Problems lists everything in the snapshot that is not healthy, most severe first
+func (s *Snapshot) Problems() []Problem {
+	var problems []Problem
+	add := func(severity Severity, component, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Severity:  severity,
+			Component: component,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	sections := make([]string, 0, len(s.Errors))
+	for section := range s.Errors {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		add(SeverityWarning, section, "collection failed: %s", s.Errors[section])
+	}
+
+	for _, ctrl := range s.Controllers {
+		component := fmt.Sprintf("controller %d", ctrl.AdapterIndex)
+		if ctrl.ControllerStatus != "" && !strings.EqualFold(ctrl.ControllerStatus, "optimal") {
+			add(SeverityCritical, component, "status is %s", ctrl.ControllerStatus)
+		}
+	}
+
+	for _, vd := range s.VirtualDrives {
+		component := fmt.Sprintf("vd %d/%d", vd.AdapterIndex, vd.TargetId)
+		if state := vd.NormalizedState(); state.Severity() != SeverityOK {
+			add(state.Severity(), component, "state is %s", state)
+		}
+	}
+
+	for _, pd := range s.PhysicalDrives {
+		component := fmt.Sprintf("pd %d/%s", pd.AdapterIndex, pd.Slot())
+		if state := pd.NormalizedState(); state.Severity() != SeverityOK {
+			add(state.Severity(), component, "state is %s", state)
+		}
+		if pd.PredictiveFailureCount > 0 {
+			add(SeverityWarning, component, "%d predictive failures", pd.PredictiveFailureCount)
+		}
+		if pd.MediaErrorCount > 0 {
+			add(SeverityWarning, component, "%d media errors", pd.MediaErrorCount)
+		}
+		if strings.EqualFold(pd.SMARTAlertFlagged, "yes") {
+			add(SeverityWarning, component, "SMART alert flagged by drive")
+		}
+	}
+
+	for _, bbu := range s.Batteries {
+		component := fmt.Sprintf("%s %d", bbu.Kind(), bbu.AdapterIndex)
+		if bbu.BatteryState != "" && !strings.EqualFold(bbu.BatteryState, "optimal") {
+			add(SeverityWarning, component, "state is %s", bbu.BatteryState)
+		}
+		if strings.EqualFold(bbu.ReplacementRequired, "yes") {
+			add(SeverityCritical, component, "replacement required")
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Severity > problems[j].Severity
+	})
+	return problems
+}
+
+// Severity returns the most severe problem in the snapshot
+func (s *Snapshot) Severity() Severity {
+	severity := SeverityOK
+	for _, problem := range s.Problems() {
+		if problem.Severity > severity {
+			severity = problem.Severity
+		}
+	}
+	return severity
+}
@@ -19,8 +19,13 @@ func parseFiled(line, key, fieldType string) (interface{}, error) {
 			return 0, fmt.Errorf("empty value for key %s", key)
 		}
 		
-		// Try to parse the first part as integer
-		intVal, err := strconv.Atoi(parts[0])
+		// Try to parse the leading digits of the first part as integer,
+		// MegaCLI glues units on: "512MB", "30%"
+		number := parts[0]
+		if end := strings.IndexFunc(number, func(r rune) bool { return r < '0' || r > '9' }); end > 0 {
+			number = number[:end]
+		}
+		intVal, err := strconv.Atoi(number)
 		if err != nil {
 			return 0, fmt.Errorf("failed to parse int value '%s' for key %s: %v", parts[0], key, err)
 		}
@@ -47,18 +52,51 @@ func parseFiled(line, key, fieldType string) (interface{}, error) {
 	}
 }
 
+// normalizeLine removes the padding MegaCLI puts before the colon of
+// "Key    : value" lines, so keys can be matched by prefix
+func normalizeLine(line string) string {
+	idx := strings.Index(line, ":")
+	if idx <= 0 {
+		return line
+	}
+	return strings.TrimRight(line[:idx], " \t") + line[idx:]
+}
+
+// parseAdapterIndex returns the adapter number from "Adapter #0" or
+// "BBU status for Adapter: 0" lines
+func parseAdapterIndex(line, key string) (int, bool) {
+	idx := strings.Index(line, key)
+	if idx < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(line[idx+len(key):])
+	if len(fields) == 0 {
+		return 0, false
+	}
+	adapter, err := strconv.Atoi(strings.TrimSuffix(fields[0], ":"))
+	if err != nil {
+		return 0, false
+	}
+	return adapter, true
+}
+
 // ParsePhysicalDriveInfo parses MegaCLI output for physical drive information
 func ParsePhysicalDriveInfo(output string) ([]*PhysicalDriveStat, error) {
 	var drives []*PhysicalDriveStat
 	var currentDrive *PhysicalDriveStat
+	adapter := 0
 	
 	lines := strings.Split(output, "\n")
 	
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		line = normalizeLine(strings.TrimSpace(line))
 		if line == "" {
 			continue
 		}
+		if index, ok := parseAdapterIndex(line, keyCtrlAdapter); ok {
+			adapter = index
+			continue
+		}
 		
 		// Check if this is the start of a new drive section
 		if strings.Contains(line, "Enclosure Device ID:") && currentDrive != nil {
@@ -68,7 +106,7 @@ func ParsePhysicalDriveInfo(output string) ([]*PhysicalDriveStat, error) {
 		
 		// Initialize new drive if we encounter enclosure device ID
 		if strings.HasPrefix(line, keyPdEnclosureDeviceId) && currentDrive == nil {
-			currentDrive = &PhysicalDriveStat{}
+			currentDrive = &PhysicalDriveStat{AdapterIndex: adapter}
 		}
 		
 		// Parse the line if we have a current drive
@@ -91,21 +129,25 @@ func ParsePhysicalDriveInfo(output string) ([]*PhysicalDriveStat, error) {
 func ParseBatteryInfo(output string) ([]*BatteryBackupStat, error) {
 	var batteries []*BatteryBackupStat
 	var currentBattery *BatteryBackupStat
+	adapter := 0
 	
 	lines := strings.Split(output, "\n")
 	
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		line = normalizeLine(strings.TrimSpace(line))
 		if line == "" {
 			continue
 		}
+		if index, ok := parseAdapterIndex(line, keyBbuStatusForAdapter); ok {
+			adapter = index
+		}
 		
 		// Initialize new battery section
-		if strings.Contains(line, "BBU status for Adapter:") || strings.Contains(line, "Battery Type:") {
+		if strings.Contains(line, keyBbuStatusForAdapter) || strings.Contains(line, keyBbuBatteryType) {
 			if currentBattery != nil {
 				batteries = append(batteries, currentBattery)
 			}
-			currentBattery = &BatteryBackupStat{}
+			currentBattery = &BatteryBackupStat{AdapterIndex: adapter}
 		}
 		
 		if currentBattery != nil {
@@ -130,17 +172,23 @@ func ParseControllerInfo(output string) ([]*ControllerStat, error) {
 	lines := strings.Split(output, "\n")
 	
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		line = normalizeLine(strings.TrimSpace(line))
 		if line == "" {
 			continue
 		}
 		
-		// Initialize new controller section
-		if strings.Contains(line, "Adapter #") || strings.HasPrefix(line, keyCtrlProductName) {
+		// Initialize new controller section. "Adapter #N" starts one; a
+		// product name only does when the output has no adapter headers.
+		if index, ok := parseAdapterIndex(line, keyCtrlAdapter); ok {
+			if currentController != nil {
+				controllers = append(controllers, currentController)
+			}
+			currentController = &ControllerStat{AdapterIndex: index}
+		} else if strings.HasPrefix(line, keyCtrlProductName) && (currentController == nil || currentController.ProductName != "") {
 			if currentController != nil {
 				controllers = append(controllers, currentController)
 			}
-			currentController = &ControllerStat{}
+			currentController = &ControllerStat{AdapterIndex: len(controllers)}
 		}
 		
 		if currentController != nil {
@@ -161,21 +209,26 @@ func ParseControllerInfo(output string) ([]*ControllerStat, error) {
 func ParseVirtualDriveInfo(output string) ([]*VirtualDriveStat, error) {
 	var virtualDrives []*VirtualDriveStat
 	var currentVD *VirtualDriveStat
+	adapter := 0
 	
 	lines := strings.Split(output, "\n")
 	
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		line = normalizeLine(strings.TrimSpace(line))
 		if line == "" {
 			continue
 		}
+		if index, ok := parseAdapterIndex(line, keyCtrlAdapter); ok {
+			adapter = index
+			continue
+		}
 		
 		// Initialize new virtual drive section
-		if strings.Contains(line, "Virtual Drive:") || strings.HasPrefix(line, keyVdTargetId) {
+		if strings.Contains(line, keyVdVirtualDrive) || strings.HasPrefix(line, keyVdTargetId) {
 			if currentVD != nil {
 				virtualDrives = append(virtualDrives, currentVD)
 			}
-			currentVD = &VirtualDriveStat{}
+			currentVD = &VirtualDriveStat{AdapterIndex: adapter}
 		}
 		
 		if currentVD != nil {
@@ -6,6 +6,7 @@ import (
 
 // PhysicalDriveStat represents the statistics of a physical drive
 type PhysicalDriveStat struct {
+	AdapterIndex             int    `json:"adapter_index"`
 	EnclosureDeviceId        int    `json:"enclosure_device_id"`
 	DeviceId                 int    `json:"device_id"`
 	SlotNumber               int    `json:"slot_number"`
@@ -13,11 +14,14 @@ type PhysicalDriveStat struct {
 	OtherErrorCount          int    `json:"other_error_count"`
 	PredictiveFailureCount   int    `json:"predictive_failure_count"`
 	Pdtype                   string `json:"pd_type"`
+	MediaType                string `json:"media_type"`
 	RawSize                  string `json:"raw_size"`
 	FirmwareState            string `json:"firmware_state"`
 	SerialNumber             string `json:"serial_number"`
+	WWN                      string `json:"wwn"`
 	Model                    string `json:"model"`
 	Brand                    string `json:"brand"`
+	FirmwareLevel            string `json:"firmware_level"`
 	DriveTemperature         string `json:"drive_temperature"`
 	SMARTFlag                string `json:"smart_flag"`
 	SMARTAlertFlagged        string `json:"smart_alert_flagged"`
@@ -67,6 +71,12 @@ func (p *PhysicalDriveStat) parseLine(line string) error {
 			return err
 		}
 		p.Pdtype = pdtype.(string)
+	} else if strings.HasPrefix(line, keyPdMediaType) {
+		mediaType, err := parseFiled(line, keyPdMediaType, typeString)
+		if err != nil {
+			return err
+		}
+		p.MediaType = mediaType.(string)
 	} else if strings.HasPrefix(line, keyPdRawSize) {
 		rawSize, err := parseFiled(line, keyPdRawSize, typeString)
 		if err != nil {
@@ -100,6 +110,18 @@ func (p *PhysicalDriveStat) parseLine(line string) error {
 		} else if len(parts) == 1 {
 			p.SerialNumber = parts[0]
 		}
+	} else if strings.HasPrefix(line, keyPdWWN) {
+		wwn, err := parseFiled(line, keyPdWWN, typeString)
+		if err != nil {
+			return err
+		}
+		p.WWN = wwn.(string)
+	} else if strings.HasPrefix(line, keyPdFirmwareLevel) {
+		firmwareLevel, err := parseFiled(line, keyPdFirmwareLevel, typeString)
+		if err != nil {
+			return err
+		}
+		p.FirmwareLevel = firmwareLevel.(string)
 	} else if strings.HasPrefix(line, keyPdDriveTemperature) {
 		driveTemperature, err := parseFiled(line, keyPdDriveTemperature, typeString)
 		if err != nil {
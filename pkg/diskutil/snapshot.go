@@ -0,0 +1,127 @@
+package diskutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot is the normalized view of every controller on a host, as
+// collected by one of the backends in pkg/backend
+type Snapshot struct {
+	CollectedAt    time.Time            `json:"collected_at"`
+	Backend        string               `json:"backend"`
+	Controllers    []*ControllerStat    `json:"controllers"`
+	VirtualDrives  []*VirtualDriveStat  `json:"virtual_drives"`
+	PhysicalDrives []*PhysicalDriveStat `json:"physical_drives"`
+	Batteries      []*BatteryBackupStat `json:"batteries"`
+	// Errors maps a section (controller, vd, pd, bbu) to the error that
+	// prevented it from being collected
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// NewSnapshot returns an empty snapshot stamped with the current time
+func NewSnapshot(backend string) *Snapshot {
+	return &Snapshot{
+		CollectedAt: time.Now(),
+		Backend:     backend,
+		Errors:      make(map[string]string),
+	}
+}
+
+// SetError records that section could not be collected
+func (s *Snapshot) SetError(section string, err error) {
+	if s.Errors == nil {
+		s.Errors = make(map[string]string)
+	}
+	s.Errors[section] = err.Error()
+}
+
+// Slot returns the drive position as "enclosure:slot"
+func (p *PhysicalDriveStat) Slot() string {
+	return fmt.Sprintf("%d:%d", p.EnclosureDeviceId, p.SlotNumber)
+}
+
+// Identity returns the WWN of the drive, or its serial number when the
+// WWN is not reported, or "" when neither is known
+func (p *PhysicalDriveStat) Identity() string {
+	if p.WWN != "" {
+		return p.WWN
+	}
+	return p.SerialNumber
+}
+
+// Kind returns "cachevault" for CacheVault flash modules and "bbu" for
+// battery backup units
+func (b *BatteryBackupStat) Kind() string {
+	batteryType := strings.ToUpper(b.BatteryType)
+	if strings.HasPrefix(batteryType, "CVPM") || strings.Contains(batteryType, "CACHEVAULT") {
+		return "cachevault"
+	}
+	return "bbu"
+}
+
+// ParseTemperature returns the leading number of a temperature such as
+// "35C", "35C (95.00 F)" or "62 degree Celcius", or 0 when there is none
+func ParseTemperature(temp string) float64 {
+	temp = strings.TrimSpace(temp)
+	end := 0
+	for end < len(temp) && (temp[end] >= '0' && temp[end] <= '9' || temp[end] == '.') {
+		end++
+	}
+	value, err := strconv.ParseFloat(temp[:end], 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// ParseSize converts a size such as "1.089 TB" or "278.875 GB [0x22ecb25c Sectors]"
+// to bytes, or returns 0 when it cannot be parsed
+func ParseSize(size string) float64 {
+	if idx := strings.Index(size, "["); idx >= 0 {
+		size = size[:idx]
+	}
+	size = strings.TrimSpace(size)
+	if size == "" || size == "N/A" {
+		return 0
+	}
+
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"PB", 1 << 50},
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+	} {
+		if strings.HasSuffix(size, unit.suffix) {
+			multiplier = unit.multiplier
+			size = strings.TrimSuffix(size, unit.suffix)
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(size), 64)
+	if err != nil {
+		return 0
+	}
+	return value * multiplier
+}
+
+// ParseCount converts an error counter to an int, treating "-" and "N/A" as 0
+func ParseCount(count string) int {
+	count = strings.TrimSpace(count)
+	if count == "" || count == "N/A" || count == "-" {
+		return 0
+	}
+	value, err := strconv.Atoi(count)
+	if err != nil {
+		return 0
+	}
+	return value
+}
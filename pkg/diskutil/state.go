@@ -0,0 +1,121 @@
+package diskutil
+
+import (
+	"strings"
+)
+
+// PDState is the normalized state of a physical drive. storcli reports short
+// codes ("Onln", "UBad") while MegaCLI prints firmware states ("Online, Spun Up",
+// "Unconfigured(bad)"); both map onto the same set of values.
+type PDState string
+
+const (
+	PDStateOnline           PDState = "online"
+	PDStateOffline          PDState = "offline"
+	PDStateRebuild          PDState = "rebuild"
+	PDStateCopyback         PDState = "copyback"
+	PDStateFailed           PDState = "failed"
+	PDStateMissing          PDState = "missing"
+	PDStateUnconfiguredGood PDState = "unconfigured_good"
+	PDStateUnconfiguredBad  PDState = "unconfigured_bad"
+	PDStateHotspare         PDState = "hotspare"
+	PDStateJBOD             PDState = "jbod"
+	PDStateUnknown          PDState = "unknown"
+)
+
+// PDStates lists every physical drive state, in export order
+var PDStates = []PDState{
+	PDStateOnline,
+	PDStateOffline,
+	PDStateRebuild,
+	PDStateCopyback,
+	PDStateFailed,
+	PDStateMissing,
+	PDStateUnconfiguredGood,
+	PDStateUnconfiguredBad,
+	PDStateHotspare,
+	PDStateJBOD,
+	PDStateUnknown,
+}
+
+// VDState is the normalized state of a virtual drive
+type VDState string
+
+const (
+	VDStateOptimal           VDState = "optimal"
+	VDStatePartiallyDegraded VDState = "partially_degraded"
+	VDStateDegraded          VDState = "degraded"
+	VDStateOffline           VDState = "offline"
+	VDStateRecovery          VDState = "recovery"
+	VDStateUnknown           VDState = "unknown"
+)
+
+// VDStates lists every virtual drive state, in export order
+var VDStates = []VDState{
+	VDStateOptimal,
+	VDStatePartiallyDegraded,
+	VDStateDegraded,
+	VDStateOffline,
+	VDStateRecovery,
+	VDStateUnknown,
+}
+
+// ParsePDState normalizes a storcli or MegaCLI physical drive state
+func ParsePDState(raw string) PDState {
+	// MegaCLI appends the spin state: "Online, Spun Up"
+	state := strings.ToLower(strings.TrimSpace(raw))
+	if idx := strings.Index(state, ","); idx >= 0 {
+		state = strings.TrimSpace(state[:idx])
+	}
+
+	switch state {
+	case "onln", "online":
+		return PDStateOnline
+	case "offln", "offline":
+		return PDStateOffline
+	case "rbld", "rebuild":
+		return PDStateRebuild
+	case "cpybck", "copyback":
+		return PDStateCopyback
+	case "f", "failed":
+		return PDStateFailed
+	case "msng", "missing":
+		return PDStateMissing
+	case "ugood", "ugunsp", "ugshld", "unconfigured(good)":
+		return PDStateUnconfiguredGood
+	case "ubad", "ubunsp", "unconfigured(bad)":
+		return PDStateUnconfiguredBad
+	case "dhs", "ghs", "hotspare", "hot spare":
+		return PDStateHotspare
+	case "jbod":
+		return PDStateJBOD
+	}
+	return PDStateUnknown
+}
+
+// ParseVDState normalizes a storcli or MegaCLI virtual drive state
+func ParseVDState(raw string) VDState {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "optl", "optimal":
+		return VDStateOptimal
+	case "pdgd", "partially degraded":
+		return VDStatePartiallyDegraded
+	case "dgrd", "degraded":
+		return VDStateDegraded
+	case "ofln", "offline":
+		return VDStateOffline
+	case "rec", "recovery":
+		return VDStateRecovery
+	}
+	return VDStateUnknown
+}
+
+// NormalizedState returns the drive's firmware state as a PDState
+func (p *PhysicalDriveStat) NormalizedState() PDState {
+	return ParsePDState(p.FirmwareState)
+}
+
+// NormalizedState returns the virtual drive's state as a VDState
+func (v *VirtualDriveStat) NormalizedState() VDState {
+	return ParseVDState(v.State)
+}
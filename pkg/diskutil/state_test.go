@@ -0,0 +1,58 @@
+package diskutil
+
+import "testing"
+
+func TestParsePDState(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want PDState
+	}{
+		{"Onln", PDStateOnline},
+		{"Online, Spun Up", PDStateOnline},
+		{"Offln", PDStateOffline},
+		{"Rbld", PDStateRebuild},
+		{"Rebuild", PDStateRebuild},
+		{"Copyback", PDStateCopyback},
+		{"F", PDStateFailed},
+		{"Failed", PDStateFailed},
+		{"Msng", PDStateMissing},
+		{"UGood", PDStateUnconfiguredGood},
+		{"Unconfigured(good), Spun down", PDStateUnconfiguredGood},
+		{"UBad", PDStateUnconfiguredBad},
+		{"Unconfigured(bad)", PDStateUnconfiguredBad},
+		{"DHS", PDStateHotspare},
+		{"Hotspare, Spun Up", PDStateHotspare},
+		{"JBOD", PDStateJBOD},
+		{"  onln  ", PDStateOnline},
+		{"", PDStateUnknown},
+		{"Sntze", PDStateUnknown},
+	}
+	for _, tt := range tests {
+		if got := ParsePDState(tt.raw); got != tt.want {
+			t.Errorf("ParsePDState(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseVDState(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want VDState
+	}{
+		{"Optl", VDStateOptimal},
+		{"Optimal", VDStateOptimal},
+		{"Pdgd", VDStatePartiallyDegraded},
+		{"Partially Degraded", VDStatePartiallyDegraded},
+		{"Dgrd", VDStateDegraded},
+		{"Degraded", VDStateDegraded},
+		{"OfLn", VDStateOffline},
+		{"Rec", VDStateRecovery},
+		{"", VDStateUnknown},
+		{"Cac", VDStateUnknown},
+	}
+	for _, tt := range tests {
+		if got := ParseVDState(tt.raw); got != tt.want {
+			t.Errorf("ParseVDState(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package diskutil
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// MegaCLI names virtual drives as "Virtual Drive: 0 (Target Id: 0)"
+var vdTargetIdPattern = regexp.MustCompile(`Target Id: (\d+)`)
+
 // VirtualDriveStat represents the statistics of a virtual drive (RAID array)
 type VirtualDriveStat struct {
+	AdapterIndex        int    `json:"adapter_index"`
 	TargetId            int    `json:"target_id"`
 	Name                string `json:"name"`
 	RAID_Level          string `json:"raid_level"`
@@ -24,7 +30,15 @@ type VirtualDriveStat struct {
 }
 
 func (v *VirtualDriveStat) parseLine(line string) error {
-	if strings.HasPrefix(line, keyVdTargetId) {
+	if strings.HasPrefix(line, keyVdVirtualDrive) {
+		if match := vdTargetIdPattern.FindStringSubmatch(line); match != nil {
+			targetId, err := strconv.Atoi(match[1])
+			if err != nil {
+				return err
+			}
+			v.TargetId = targetId
+		}
+	} else if strings.HasPrefix(line, keyVdTargetId) {
 		targetId, err := parseFiled(line, keyVdTargetId, typeInt)
 		if err != nil {
 			return err
@@ -66,6 +80,36 @@ func (v *VirtualDriveStat) parseLine(line string) error {
 			return err
 		}
 		v.NumberOfDrives = numberOfDrives.(int)
+	} else if strings.HasPrefix(line, keyVdSpanDepth) {
+		spanDepth, err := parseFiled(line, keyVdSpanDepth, typeInt)
+		if err != nil {
+			return err
+		}
+		v.SpanDepth = spanDepth.(int)
+	} else if strings.HasPrefix(line, keyVdDefaultCachePolicy) {
+		defaultCachePolicy, err := parseFiled(line, keyVdDefaultCachePolicy, typeString)
+		if err != nil {
+			return err
+		}
+		v.DefaultCachePolicy = defaultCachePolicy.(string)
+	} else if strings.HasPrefix(line, keyVdCurrentCachePolicy) {
+		currentCachePolicy, err := parseFiled(line, keyVdCurrentCachePolicy, typeString)
+		if err != nil {
+			return err
+		}
+		v.CurrentCachePolicy = currentCachePolicy.(string)
+	} else if strings.HasPrefix(line, keyVdAccessPolicy) {
+		accessPolicy, err := parseFiled(line, keyVdAccessPolicy, typeString)
+		if err != nil {
+			return err
+		}
+		v.AccessPolicy = accessPolicy.(string)
+	} else if strings.HasPrefix(line, keyVdDiskCachePolicy) {
+		diskCachePolicy, err := parseFiled(line, keyVdDiskCachePolicy, typeString)
+		if err != nil {
+			return err
+		}
+		v.DiskCachePolicy = diskCachePolicy.(string)
 	} else if strings.HasPrefix(line, keyVdBadBlocksExist) {
 		badBlocksExist, err := parseFiled(line, keyVdBadBlocksExist, typeString)
 		if err != nil {
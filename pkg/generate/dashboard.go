@@ -0,0 +1,162 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// panel is the subset of the Grafana panel model the dashboard uses
+type panel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Datasource  map[string]string      `json:"datasource"`
+	GridPos     gridPos                `json:"gridPos"`
+	Targets     []target               `json:"targets"`
+	FieldConfig map[string]interface{} `json:"fieldConfig,omitempty"`
+	Options     map[string]interface{} `json:"options,omitempty"`
+}
+
+type gridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+	Format       string `json:"format,omitempty"`
+}
+
+// Dashboard returns a Grafana dashboard for the metrics in opts. It has a
+// datasource and an instance variable so it can be imported as is.
+func Dashboard(opts Options) ([]byte, error) {
+	m := newMetrics(opts.Namespace)
+	datasource := map[string]string{"type": "prometheus", "uid": "${datasource}"}
+	sel := `instance=~"$instance"`
+
+	// thresholds colours values green, orange from warning and red from critical
+	thresholds := func(unit string, warning, critical float64) map[string]interface{} {
+		return map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"unit": unit,
+				"thresholds": map[string]interface{}{
+					"mode": "absolute",
+					"steps": []map[string]interface{}{
+						{"color": "green", "value": nil},
+						{"color": "orange", "value": warning},
+						{"color": "red", "value": critical},
+					},
+				},
+			},
+		}
+	}
+
+	var panels []panel
+	add := func(p panel) {
+		p.ID = len(panels) + 1
+		p.Datasource = datasource
+		for i := range p.Targets {
+			p.Targets[i].RefID = string(rune('A' + i))
+		}
+		panels = append(panels, p)
+	}
+
+	// Summary row
+	add(panel{Type: "stat", Title: "Virtual drives not optimal", GridPos: gridPos{H: 4, W: 6, X: 0, Y: 0},
+		Targets:     []target{{Expr: fmt.Sprintf(`count(%s{%s} == 0) or vector(0)`, m.name("vd", "status"), sel), Instant: true}},
+		FieldConfig: thresholds("none", 1, 1)})
+	add(panel{Type: "stat", Title: "Drives not online", GridPos: gridPos{H: 4, W: 6, X: 6, Y: 0},
+		Targets:     []target{{Expr: fmt.Sprintf(`count(%s{%s} == 0) or vector(0)`, m.name("pd", "status"), sel), Instant: true}},
+		FieldConfig: thresholds("none", 1, 1)})
+	add(panel{Type: "stat", Title: "Batteries not optimal", GridPos: gridPos{H: 4, W: 6, X: 12, Y: 0},
+		Targets:     []target{{Expr: fmt.Sprintf(`count(%s{%s} == 0) or vector(0)`, m.name("bbu", "status"), sel), Instant: true}},
+		FieldConfig: thresholds("none", 1, 1)})
+	add(panel{Type: "stat", Title: "Failing collectors", GridPos: gridPos{H: 4, W: 6, X: 18, Y: 0},
+		Targets:     []target{{Expr: fmt.Sprintf(`count(%s{%s} == 0) or vector(0)`, m.name("scrape", "collector_success"), sel), Instant: true}},
+		FieldConfig: thresholds("none", 1, 1)})
+
+	// State tables
+	add(panel{Type: "table", Title: "Virtual drive states", GridPos: gridPos{H: 8, W: 12, X: 0, Y: 4},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s} == 1`, m.name("vd", "state"), sel), Instant: true, Format: "table"}},
+		Options: map[string]interface{}{"showHeader": true}})
+	add(panel{Type: "table", Title: "Drive states", GridPos: gridPos{H: 8, W: 12, X: 12, Y: 4},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s} == 1`, m.name("pd", "state"), sel), Instant: true, Format: "table"}},
+		Options: map[string]interface{}{"showHeader": true}})
+
+	// Temperatures
+	add(panel{Type: "timeseries", Title: "Drive temperature", GridPos: gridPos{H: 8, W: 8, X: 0, Y: 12},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s}`, m.name("pd", "temperature_celsius"), sel),
+			LegendFormat: "{{instance}} {{controller}}/{{enclosure_slot}}"}},
+		FieldConfig: thresholds("celsius", float64(opts.DriveTemp), float64(opts.DriveTemp+10))})
+	add(panel{Type: "timeseries", Title: "Controller temperature", GridPos: gridPos{H: 8, W: 8, X: 8, Y: 12},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s}`, m.name("controller", "temperature_celsius"), sel),
+			LegendFormat: "{{instance}} controller {{controller}}"}},
+		FieldConfig: thresholds("celsius", float64(opts.ControllerTemp), float64(opts.ControllerTemp+10))})
+	add(panel{Type: "timeseries", Title: "Battery temperature", GridPos: gridPos{H: 8, W: 8, X: 16, Y: 12},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s}`, m.name("bbu", "temperature_celsius"), sel),
+			LegendFormat: "{{instance}} {{type}} {{controller}}"}},
+		FieldConfig: thresholds("celsius", float64(opts.BBUTemp), float64(opts.BBUTemp+10))})
+
+	// Error counters
+	add(panel{Type: "timeseries", Title: "Media errors per hour", GridPos: gridPos{H: 8, W: 8, X: 0, Y: 20},
+		Targets: []target{{Expr: fmt.Sprintf(`increase(%s{%s}[1h])`, m.name("pd", "media_errors_total"), sel),
+			LegendFormat: "{{instance}} {{controller}}/{{enclosure_slot}}"}}})
+	add(panel{Type: "timeseries", Title: "Other errors per hour", GridPos: gridPos{H: 8, W: 8, X: 8, Y: 20},
+		Targets: []target{{Expr: fmt.Sprintf(`increase(%s{%s}[1h])`, m.name("pd", "other_errors_total"), sel),
+			LegendFormat: "{{instance}} {{controller}}/{{enclosure_slot}}"}}})
+	add(panel{Type: "timeseries", Title: "Predictive failures", GridPos: gridPos{H: 8, W: 8, X: 16, Y: 20},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s}`, m.name("pd", "predictive_failures_total"), sel),
+			LegendFormat: "{{instance}} {{controller}}/{{enclosure_slot}}"}}})
+
+	// Inventory
+	add(panel{Type: "table", Title: "Drives", GridPos: gridPos{H: 8, W: 24, X: 0, Y: 28},
+		Targets: []target{{Expr: fmt.Sprintf(`%s{%s}`, m.name("pd", "info"), sel), Instant: true, Format: "table"}},
+		Options: map[string]interface{}{"showHeader": true}})
+
+	dashboard := map[string]interface{}{
+		"title":         "MegaRAID",
+		"uid":           "megaraid-exporter",
+		"tags":          []string{"megaraid", "raid"},
+		"editable":      true,
+		"schemaVersion": 38,
+		"refresh":       "1m",
+		"time":          map[string]string{"from": "now-24h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []map[string]interface{}{
+				{
+					"name":  "datasource",
+					"label": "Data source",
+					"type":  "datasource",
+					"query": "prometheus",
+				},
+				{
+					"name":       "instance",
+					"label":      "Instance",
+					"type":       "query",
+					"datasource": datasource,
+					"query":      fmt.Sprintf("label_values(%s, instance)", m.name("scrape", "collector_success")),
+					"refresh":    2,
+					"multi":      true,
+					"includeAll": true,
+					"current":    map[string]interface{}{"text": "All", "value": "$__all"},
+				},
+			},
+		},
+		"panels": panels,
+	}
+	if err := m.check(opts.Exported); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dashboard: %v", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,60 @@
+package generate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options controls the generated rules and dashboard
+type Options struct {
+	// Namespace is the metrics.namespace the exporter runs with
+	Namespace string
+	// Exported lists the metric names this build exports. Every metric the
+	// generated files reference must be in it.
+	Exported []string
+	// Job is the Prometheus job that scrapes the exporter
+	Job string
+	// Temperatures (Celsius) that raise a high-temperature alert
+	DriveTemp      int
+	ControllerTemp int
+	BBUTemp        int
+}
+
+// metrics builds metric names from the namespace and remembers them, so that
+// references to metrics the exporter does not export are caught
+type metrics struct {
+	namespace string
+	used      map[string]bool
+}
+
+func newMetrics(namespace string) *metrics {
+	return &metrics{namespace: namespace, used: make(map[string]bool)}
+}
+
+func (m *metrics) name(subsystem, name string) string {
+	fqName := prometheus.BuildFQName(m.namespace, subsystem, name)
+	m.used[fqName] = true
+	return fqName
+}
+
+// check returns an error naming the referenced metrics that are not exported
+func (m *metrics) check(exported []string) error {
+	known := make(map[string]bool)
+	for _, name := range exported {
+		known[name] = true
+	}
+	var missing []string
+	for name := range m.used {
+		if !known[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("metrics not exported by this build: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []rule `yaml:"rules"`
+}
+
+type rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// Rules returns Prometheus alerting rules for the metrics in opts
+func Rules(opts Options) ([]byte, error) {
+	m := newMetrics(opts.Namespace)
+	alert := func(name, severity, expr, duration, summary, description string) rule {
+		return rule{
+			Alert:  name,
+			Expr:   expr,
+			For:    duration,
+			Labels: map[string]string{"severity": severity},
+			Annotations: map[string]string{
+				"summary":     summary,
+				"description": description,
+			},
+		}
+	}
+
+	rules := []rule{
+		alert("MegaRAIDVirtualDriveDegraded", "critical",
+			fmt.Sprintf(`%s{state=~"degraded|partially_degraded|offline"} == 1`, m.name("vd", "state")),
+			"1m",
+			"Virtual drive {{ $labels.controller }}/{{ $labels.vd }} is {{ $labels.state }}",
+			"Virtual drive {{ $labels.vd }} on controller {{ $labels.controller }} of {{ $labels.instance }} is {{ $labels.state }}. Replace the failed drive or check the rebuild."),
+		alert("MegaRAIDPhysicalDriveFailed", "critical",
+			fmt.Sprintf(`%s{state=~"failed|offline|missing|unconfigured_bad"} == 1`, m.name("pd", "state")),
+			"1m",
+			"Drive {{ $labels.enclosure_slot }} on controller {{ $labels.controller }} is {{ $labels.state }}",
+			"Physical drive {{ $labels.enclosure_slot }} on controller {{ $labels.controller }} of {{ $labels.instance }} is {{ $labels.state }}."),
+		alert("MegaRAIDVirtualDriveFlapping", "warning",
+			fmt.Sprintf(`sum by (instance, controller, vd) (increase(%s[1h])) > 2`, m.name("vd", "state_transitions_total")),
+			"",
+			"Virtual drive {{ $labels.controller }}/{{ $labels.vd }} keeps changing state",
+			"Virtual drive {{ $labels.vd }} on controller {{ $labels.controller }} of {{ $labels.instance }} changed state {{ $value }} times in the last hour."),
+		alert("MegaRAIDPhysicalDriveFlapping", "warning",
+			fmt.Sprintf(`changes(%s[1h]) > 2`, m.name("pd", "state_last_change_timestamp_seconds")),
+			"",
+			"Drive {{ $labels.enclosure_slot }} keeps changing state",
+			"Drive {{ $labels.enclosure_slot }} on controller {{ $labels.controller }} of {{ $labels.instance }} changed state {{ $value }} times in the last hour. Check the drive, its cable and the backplane."),
+		alert("MegaRAIDMediaErrorsIncreasing", "warning",
+			fmt.Sprintf(`increase(%s[1h]) > 0`, m.name("pd", "media_errors_total")),
+			"",
+			"Media errors on drive {{ $labels.enclosure_slot }} are increasing",
+			"Drive {{ $labels.enclosure_slot }} on controller {{ $labels.controller }} of {{ $labels.instance }} logged {{ $value }} media errors in the last hour."),
+		alert("MegaRAIDPredictiveFailure", "warning",
+			fmt.Sprintf(`increase(%s[1h]) > 0`, m.name("pd", "predictive_failures_total")),
+			"",
+			"Drive {{ $labels.enclosure_slot }} reports predictive failures",
+			"Drive {{ $labels.enclosure_slot }} on controller {{ $labels.controller }} of {{ $labels.instance }} reported a predictive failure. Plan a replacement."),
+		alert("MegaRAIDBatteryReplacementRequired", "warning",
+			fmt.Sprintf(`%s == 1`, m.name("bbu", "replacement_required")),
+			"5m",
+			"The {{ $labels.type }} on controller {{ $labels.controller }} needs replacing",
+			"The controller reports that the {{ $labels.type }} on controller {{ $labels.controller }} of {{ $labels.instance }} must be replaced. Write-back caching may be disabled."),
+		alert("MegaRAIDBatteryNotOptimal", "warning",
+			fmt.Sprintf(`%s == 0`, m.name("bbu", "status")),
+			"30m",
+			"The {{ $labels.type }} on controller {{ $labels.controller }} is not optimal",
+			"The {{ $labels.type }} on controller {{ $labels.controller }} of {{ $labels.instance }} has not been optimal for 30 minutes. A learn cycle that does not finish points to a worn battery."),
+		alert("MegaRAIDDriveTemperatureHigh", "warning",
+			fmt.Sprintf(`%s > %d`, m.name("pd", "temperature_celsius"), opts.DriveTemp),
+			"10m",
+			"Drive {{ $labels.enclosure_slot }} is at {{ $value }}°C",
+			fmt.Sprintf("Drive {{ $labels.enclosure_slot }} on controller {{ $labels.controller }} of {{ $labels.instance }} has been above %d°C for 10 minutes.", opts.DriveTemp)),
+		alert("MegaRAIDControllerTemperatureHigh", "warning",
+			fmt.Sprintf(`%s > %d`, m.name("controller", "temperature_celsius"), opts.ControllerTemp),
+			"10m",
+			"Controller {{ $labels.controller }} is at {{ $value }}°C",
+			fmt.Sprintf("Controller {{ $labels.controller }} of {{ $labels.instance }} has been above %d°C for 10 minutes. Check the chassis airflow.", opts.ControllerTemp)),
+		alert("MegaRAIDBatteryTemperatureHigh", "warning",
+			fmt.Sprintf(`%s > %d`, m.name("bbu", "temperature_celsius"), opts.BBUTemp),
+			"10m",
+			"The {{ $labels.type }} on controller {{ $labels.controller }} is at {{ $value }}°C",
+			fmt.Sprintf("The {{ $labels.type }} on controller {{ $labels.controller }} of {{ $labels.instance }} has been above %d°C for 10 minutes.", opts.BBUTemp)),
+		alert("MegaRAIDExporterDown", "critical",
+			fmt.Sprintf(`up{job=%q} == 0`, opts.Job),
+			"5m",
+			"MegaRAID exporter on {{ $labels.instance }} is down",
+			"Prometheus has not been able to scrape the MegaRAID exporter on {{ $labels.instance }} for 5 minutes."),
+		alert("MegaRAIDCollectorFailing", "warning",
+			fmt.Sprintf(`%s == 0`, m.name("scrape", "collector_success")),
+			"10m",
+			"The {{ $labels.collector }} collector on {{ $labels.instance }} is failing",
+			"The exporter on {{ $labels.instance }} has not been able to collect {{ $labels.collector }} data for 10 minutes, so the other MegaRAID alerts are stale. Run megaraid-exporter doctor on the host."),
+	}
+
+	if err := m.check(opts.Exported); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by megaraid-exporter generate rules, do not edit\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(ruleFile{Groups: []ruleGroup{{Name: "megaraid", Rules: rules}}}); err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %v", err)
+	}
+	return buf.Bytes(), nil
+}
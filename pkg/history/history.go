@@ -0,0 +1,293 @@
+package history
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+// stateKey is the key the tracker keeps its state under in the store
+const stateKey = "history"
+
+// DriveState is the current state of a physical drive and when it was entered
+type DriveState struct {
+	Controller int              `json:"controller"`
+	Slot       string           `json:"slot"`
+	State      diskutil.PDState `json:"state"`
+	// LastChange is when the state last changed, or when the exporter first
+	// saw the drive
+	LastChange time.Time `json:"last_change"`
+}
+
+// Occupant is the drive last seen in a slot. Slots are remembered while
+// empty, so that pulling a drive and inserting another one between two
+// collections still counts as a replacement.
+type Occupant struct {
+	Controller int    `json:"controller"`
+	Slot       string `json:"slot"`
+	// Drive is the WWN or serial number of the drive, "" when the backend
+	// reports neither
+	Drive   string `json:"drive"`
+	Present bool   `json:"present"`
+	// State is the state the drive was last seen in
+	State diskutil.PDState `json:"state,omitempty"`
+	// FirstSeen is when the current drive was first seen in the slot
+	FirstSeen time.Time `json:"first_seen"`
+	// Replacements counts the times a different drive showed up in the slot
+	Replacements uint64 `json:"replacements"`
+}
+
+// Transition counts how often a virtual drive went from one state to another
+type Transition struct {
+	Controller int              `json:"controller"`
+	VD         int              `json:"vd"`
+	From       diskutil.VDState `json:"from"`
+	To         diskutil.VDState `json:"to"`
+	Count      uint64           `json:"count"`
+}
+
+type vdKey struct {
+	controller int
+	vd         int
+}
+
+type transitionKey struct {
+	vdKey
+	from, to diskutil.VDState
+}
+
+// VDState is the last seen state of a virtual drive
+type VDState struct {
+	Controller int              `json:"controller"`
+	VD         int              `json:"vd"`
+	State      diskutil.VDState `json:"state"`
+}
+
+// saved is what the tracker keeps in the store
+type saved struct {
+	Drives      []DriveState `json:"drives"`
+	Slots       []Occupant   `json:"slots"`
+	VDStates    []VDState    `json:"vd_states"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// Tracker follows drive and virtual drive states and the drive in each slot
+// across collections, so that a drive that drops offline and comes back
+// between two scrapes, or is swapped between them, still shows up in the
+// metrics. The states and counters survive restarts through the store.
+type Tracker struct {
+	store *state.Store
+
+	mu          sync.RWMutex
+	drives      map[string]*DriveState
+	slots       map[string]*Occupant
+	vdStates    map[vdKey]diskutil.VDState
+	transitions map[transitionKey]uint64
+}
+
+// New returns a tracker that continues from the state saved in store
+func New(store *state.Store) *Tracker {
+	t := &Tracker{
+		store:       store,
+		drives:      make(map[string]*DriveState),
+		slots:       make(map[string]*Occupant),
+		vdStates:    make(map[vdKey]diskutil.VDState),
+		transitions: make(map[transitionKey]uint64),
+	}
+
+	var s saved
+	if ok, err := store.Get(stateKey, &s); err != nil {
+		log.Printf("WARNING: Discarding saved state history: %v", err)
+	} else if ok {
+		for i := range s.Drives {
+			d := s.Drives[i]
+			t.drives[fmt.Sprintf("%d/%s", d.Controller, d.Slot)] = &d
+		}
+		for i := range s.Slots {
+			o := s.Slots[i]
+			t.slots[fmt.Sprintf("%d/%s", o.Controller, o.Slot)] = &o
+		}
+		for _, v := range s.VDStates {
+			t.vdStates[vdKey{controller: v.Controller, vd: v.VD}] = v.State
+		}
+		for _, tr := range s.Transitions {
+			t.transitions[transitionKey{vdKey: vdKey{controller: tr.Controller, vd: tr.VD}, from: tr.From, to: tr.To}] = tr.Count
+		}
+	}
+	return t
+}
+
+// Observe records the states in s. Sections that failed to collect are left
+// as they were.
+func (t *Tracker) Observe(s *diskutil.Snapshot) {
+	t.mu.Lock()
+	if _, failed := s.Errors[backend.SectionPD]; !failed {
+		t.observeDrives(s.PhysicalDrives, s.CollectedAt)
+	}
+	if _, failed := s.Errors[backend.SectionVD]; !failed {
+		t.observeVirtualDrives(s.VirtualDrives)
+	}
+	t.mu.Unlock()
+
+	if err := t.store.Put(stateKey, t.save()); err != nil {
+		log.Printf("ERROR: Failed to save state history: %v", err)
+	}
+}
+
+func (t *Tracker) save() saved {
+	t.mu.RLock()
+	vdStates := make([]VDState, 0, len(t.vdStates))
+	for key, state := range t.vdStates {
+		vdStates = append(vdStates, VDState{Controller: key.controller, VD: key.vd, State: state})
+	}
+	t.mu.RUnlock()
+
+	// Sorted so that an unchanged state encodes the same and is not rewritten
+	sort.Slice(vdStates, func(i, j int) bool {
+		if vdStates[i].Controller != vdStates[j].Controller {
+			return vdStates[i].Controller < vdStates[j].Controller
+		}
+		return vdStates[i].VD < vdStates[j].VD
+	})
+	return saved{Drives: t.Drives(), Slots: t.Slots(), VDStates: vdStates, Transitions: t.Transitions()}
+}
+
+func (t *Tracker) observeDrives(pds []*diskutil.PhysicalDriveStat, now time.Time) {
+	seen := make(map[string]bool)
+	for _, pd := range pds {
+		key := fmt.Sprintf("%d/%s", pd.AdapterIndex, pd.Slot())
+		seen[key] = true
+		state := pd.NormalizedState()
+
+		t.observeOccupant(key, pd, now)
+
+		current, ok := t.drives[key]
+		if !ok {
+			t.drives[key] = &DriveState{Controller: pd.AdapterIndex, Slot: pd.Slot(), State: state, LastChange: now}
+			continue
+		}
+		if current.State != state {
+			current.State = state
+			current.LastChange = now
+		}
+	}
+
+	// Drives that were pulled are no longer exported
+	for key := range t.drives {
+		if !seen[key] {
+			delete(t.drives, key)
+		}
+	}
+	for key, occupant := range t.slots {
+		if !seen[key] {
+			occupant.Present = false
+		}
+	}
+}
+
+// observeOccupant counts a replacement when the slot holds a drive with a
+// different identity than the last one seen there. A drive without WWN or
+// serial number is taken to be the one that was there before.
+func (t *Tracker) observeOccupant(key string, pd *diskutil.PhysicalDriveStat, now time.Time) {
+	drive := pd.Identity()
+	occupant, ok := t.slots[key]
+	if !ok {
+		t.slots[key] = &Occupant{Controller: pd.AdapterIndex, Slot: pd.Slot(), Drive: drive, Present: true,
+			State: pd.NormalizedState(), FirstSeen: now}
+		return
+	}
+	if drive != "" && occupant.Drive != "" && drive != occupant.Drive {
+		occupant.Replacements++
+		occupant.FirstSeen = now
+	}
+	if drive != "" {
+		occupant.Drive = drive
+	}
+	occupant.Present = true
+	occupant.State = pd.NormalizedState()
+}
+
+func (t *Tracker) observeVirtualDrives(vds []*diskutil.VirtualDriveStat) {
+	for _, vd := range vds {
+		key := vdKey{controller: vd.AdapterIndex, vd: vd.TargetId}
+		state := vd.NormalizedState()
+		if previous, ok := t.vdStates[key]; ok && previous != state {
+			t.transitions[transitionKey{vdKey: key, from: previous, to: state}]++
+		}
+		t.vdStates[key] = state
+	}
+}
+
+// Drives returns the tracked drives, sorted by controller and slot
+func (t *Tracker) Drives() []DriveState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	drives := make([]DriveState, 0, len(t.drives))
+	for _, d := range t.drives {
+		drives = append(drives, *d)
+	}
+	sort.Slice(drives, func(i, j int) bool {
+		if drives[i].Controller != drives[j].Controller {
+			return drives[i].Controller < drives[j].Controller
+		}
+		return drives[i].Slot < drives[j].Slot
+	})
+	return drives
+}
+
+// Slots returns every slot a drive was seen in, sorted by controller and
+// slot, including slots that are empty now
+func (t *Tracker) Slots() []Occupant {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	slots := make([]Occupant, 0, len(t.slots))
+	for _, o := range t.slots {
+		slots = append(slots, *o)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].Controller != slots[j].Controller {
+			return slots[i].Controller < slots[j].Controller
+		}
+		return slots[i].Slot < slots[j].Slot
+	})
+	return slots
+}
+
+// Transitions returns the virtual drive state transitions seen so far
+func (t *Tracker) Transitions() []Transition {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	transitions := make([]Transition, 0, len(t.transitions))
+	for key, count := range t.transitions {
+		transitions = append(transitions, Transition{
+			Controller: key.controller,
+			VD:         key.vd,
+			From:       key.from,
+			To:         key.to,
+			Count:      count,
+		})
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		a, b := transitions[i], transitions[j]
+		if a.Controller != b.Controller {
+			return a.Controller < b.Controller
+		}
+		if a.VD != b.VD {
+			return a.VD < b.VD
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return transitions
+}
@@ -0,0 +1,284 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+func TestObserveOccupant(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	drive := func(wwn, serial, state string) []*diskutil.PhysicalDriveStat {
+		return []*diskutil.PhysicalDriveStat{{EnclosureDeviceId: 32, SlotNumber: 1, WWN: wwn, SerialNumber: serial, FirmwareState: state}}
+	}
+
+	tests := []struct {
+		name string
+		// polls are observed an hour apart, nil means the slot is empty
+		polls        [][]*diskutil.PhysicalDriveStat
+		replacements uint64
+		present      bool
+		firstSeen    int
+		state        diskutil.PDState
+	}{
+		{
+			name:    "same drive",
+			polls:   [][]*diskutil.PhysicalDriveStat{drive("5000C500AAAA0000", "A", "Onln"), drive("5000C500AAAA0000", "A", "Onln")},
+			present: true,
+			state:   diskutil.PDStateOnline,
+		},
+		{
+			name:         "swapped between polls",
+			polls:        [][]*diskutil.PhysicalDriveStat{drive("5000C500AAAA0000", "A", "Onln"), drive("5000C500BBBB0000", "B", "Rbld")},
+			replacements: 1,
+			present:      true,
+			state:        diskutil.PDStateRebuild,
+			firstSeen:    1,
+		},
+		{
+			name:         "pulled and another drive inserted",
+			polls:        [][]*diskutil.PhysicalDriveStat{drive("", "A", "Onln"), nil, drive("", "B", "UGood")},
+			replacements: 1,
+			present:      true,
+			state:        diskutil.PDStateUnconfiguredGood,
+			firstSeen:    2,
+		},
+		{
+			name:    "pulled and put back",
+			polls:   [][]*diskutil.PhysicalDriveStat{drive("", "A", "Onln"), nil, drive("", "A", "Onln")},
+			present: true,
+			state:   diskutil.PDStateOnline,
+		},
+		{
+			name:    "pulled",
+			polls:   [][]*diskutil.PhysicalDriveStat{drive("", "A", "Onln"), nil},
+			present: false,
+			state:   diskutil.PDStateOnline,
+		},
+		{
+			name:    "identity not reported",
+			polls:   [][]*diskutil.PhysicalDriveStat{drive("", "A", "Onln"), drive("", "", "Offln"), drive("", "A", "Onln")},
+			present: true,
+			state:   diskutil.PDStateOnline,
+		},
+		{
+			name:         "replaced twice",
+			polls:        [][]*diskutil.PhysicalDriveStat{drive("", "A", "Onln"), drive("", "B", "Onln"), drive("", "C", "Onln")},
+			replacements: 2,
+			present:      true,
+			state:        diskutil.PDStateOnline,
+			firstSeen:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.Open("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker := New(store)
+			for i, pds := range tt.polls {
+				tracker.Observe(&diskutil.Snapshot{CollectedAt: start.Add(time.Duration(i) * time.Hour), PhysicalDrives: pds})
+			}
+
+			slots := tracker.Slots()
+			if len(slots) != 1 {
+				t.Fatalf("got slots %+v, want one", slots)
+			}
+			slot := slots[0]
+			if slot.Replacements != tt.replacements {
+				t.Errorf("got %d replacements, want %d", slot.Replacements, tt.replacements)
+			}
+			if slot.Present != tt.present {
+				t.Errorf("got present %v, want %v", slot.Present, tt.present)
+			}
+			if slot.State != tt.state {
+				t.Errorf("got state %s, want %s", slot.State, tt.state)
+			}
+			if want := start.Add(time.Duration(tt.firstSeen) * time.Hour); !slot.FirstSeen.Equal(want) {
+				t.Errorf("got first seen %v, want %v", slot.FirstSeen, want)
+			}
+
+			// The counters continue after a restart
+			restarted := New(store).Slots()
+			if len(restarted) != 1 || restarted[0].Replacements != tt.replacements || restarted[0].Drive != slot.Drive {
+				t.Errorf("got %+v after restart, want %+v", restarted, slot)
+			}
+		})
+	}
+}
+
+func TestObserveSkipsFailedSections(t *testing.T) {
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := New(store)
+	tracker.Observe(&diskutil.Snapshot{PhysicalDrives: []*diskutil.PhysicalDriveStat{{SerialNumber: "A", FirmwareState: "Onln"}}})
+
+	failed := diskutil.NewSnapshot("storcli")
+	failed.SetError(backend.SectionPD, errors.New("storcli timed out"))
+	tracker.Observe(failed)
+
+	if slots := tracker.Slots(); len(slots) != 1 || !slots[0].Present {
+		t.Errorf("a failed collection changed the slots: %+v", slots)
+	}
+	if drives := tracker.Drives(); len(drives) != 1 {
+		t.Errorf("a failed collection dropped the drives: %+v", drives)
+	}
+}
+
+func TestObserveDriveStates(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// states are observed an hour apart, "" means the slot is empty and
+		// "error" that the drive section failed
+		states     []string
+		state      diskutil.PDState
+		lastChange int
+		tracked    bool
+	}{
+		{name: "first seen", states: []string{"Onln"}, state: diskutil.PDStateOnline, tracked: true},
+		{name: "unchanged", states: []string{"Onln", "Onln", "Online, Spun Up"}, state: diskutil.PDStateOnline, tracked: true},
+		{name: "failed", states: []string{"Onln", "Onln", "F"}, state: diskutil.PDStateFailed, lastChange: 2, tracked: true},
+		{name: "rebuilt", states: []string{"F", "Rbld", "Rbld", "Onln"}, state: diskutil.PDStateOnline, lastChange: 3, tracked: true},
+		{name: "offline and back", states: []string{"Onln", "Offln", "Onln"}, state: diskutil.PDStateOnline, lastChange: 2, tracked: true},
+		{name: "failed collection", states: []string{"Onln", "error", "F"}, state: diskutil.PDStateFailed, lastChange: 2, tracked: true},
+		{name: "pulled", states: []string{"Onln", ""}, tracked: false},
+		{name: "pulled and put back", states: []string{"Onln", "", "Onln"}, state: diskutil.PDStateOnline, lastChange: 2, tracked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.Open("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker := New(store)
+			for i, s := range tt.states {
+				snapshot := diskutil.NewSnapshot("storcli")
+				snapshot.CollectedAt = start.Add(time.Duration(i) * time.Hour)
+				switch s {
+				case "":
+				case "error":
+					snapshot.SetError(backend.SectionPD, errors.New("storcli timed out"))
+				default:
+					snapshot.PhysicalDrives = []*diskutil.PhysicalDriveStat{{EnclosureDeviceId: 32, SlotNumber: 1, SerialNumber: "A", FirmwareState: s}}
+				}
+				tracker.Observe(snapshot)
+			}
+
+			drives := tracker.Drives()
+			if !tt.tracked {
+				if len(drives) != 0 {
+					t.Errorf("got drives %+v, want none", drives)
+				}
+				return
+			}
+			if len(drives) != 1 {
+				t.Fatalf("got drives %+v, want one", drives)
+			}
+			drive := drives[0]
+			if drive.Slot != "32:1" || drive.State != tt.state {
+				t.Errorf("got %s in %s, want %s in 32:1", drive.State, drive.Slot, tt.state)
+			}
+			if want := start.Add(time.Duration(tt.lastChange) * time.Hour); !drive.LastChange.Equal(want) {
+				t.Errorf("got last change %v, want %v", drive.LastChange, want)
+			}
+
+			// The state and its timestamp survive a restart
+			restarted := New(store).Drives()
+			if len(restarted) != 1 || restarted[0].State != drive.State || !restarted[0].LastChange.Equal(drive.LastChange) {
+				t.Errorf("got %+v after restart, want %+v", restarted, drive)
+			}
+		})
+	}
+}
+
+func TestObserveVirtualDriveTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		// states are observed in order, "error" means the virtual drive
+		// section failed
+		states      []string
+		transitions []Transition
+	}{
+		{name: "first seen", states: []string{"Optl"}},
+		{name: "unchanged", states: []string{"Optl", "Optimal", "Optl"}},
+		{
+			name:   "degraded and rebuilt",
+			states: []string{"Optl", "Dgrd", "Dgrd", "Optl"},
+			transitions: []Transition{
+				{VD: 0, From: diskutil.VDStateDegraded, To: diskutil.VDStateOptimal, Count: 1},
+				{VD: 0, From: diskutil.VDStateOptimal, To: diskutil.VDStateDegraded, Count: 1},
+			},
+		},
+		{
+			name:   "flapping",
+			states: []string{"Optl", "Pdgd", "Optl", "Pdgd", "Optl"},
+			transitions: []Transition{
+				{VD: 0, From: diskutil.VDStateOptimal, To: diskutil.VDStatePartiallyDegraded, Count: 2},
+				{VD: 0, From: diskutil.VDStatePartiallyDegraded, To: diskutil.VDStateOptimal, Count: 2},
+			},
+		},
+		{
+			name:   "failed collection",
+			states: []string{"Optl", "error", "Dgrd"},
+			transitions: []Transition{
+				{VD: 0, From: diskutil.VDStateOptimal, To: diskutil.VDStateDegraded, Count: 1},
+			},
+		},
+		{
+			name:   "unknown state",
+			states: []string{"Optl", "Cac"},
+			transitions: []Transition{
+				{VD: 0, From: diskutil.VDStateOptimal, To: diskutil.VDStateUnknown, Count: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.Open("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker := New(store)
+			for _, s := range tt.states {
+				snapshot := diskutil.NewSnapshot("storcli")
+				if s == "error" {
+					snapshot.SetError(backend.SectionVD, errors.New("storcli timed out"))
+				} else {
+					snapshot.VirtualDrives = []*diskutil.VirtualDriveStat{{TargetId: 0, State: s}}
+				}
+				tracker.Observe(snapshot)
+			}
+
+			check := func(when string, got []Transition) {
+				if len(got) != len(tt.transitions) {
+					t.Fatalf("got transitions %+v %s, want %+v", got, when, tt.transitions)
+				}
+				for i := range got {
+					if got[i] != tt.transitions[i] {
+						t.Errorf("got transition %+v %s, want %+v", got[i], when, tt.transitions[i])
+					}
+				}
+			}
+			check("", tracker.Transitions())
+
+			// The counters continue after a restart, from the last seen state
+			restarted := New(store)
+			check("after restart", restarted.Transitions())
+			last := tt.states[len(tt.states)-1]
+			restarted.Observe(&diskutil.Snapshot{VirtualDrives: []*diskutil.VirtualDriveStat{{TargetId: 0, State: last}}})
+			check("after observing the same state", restarted.Transitions())
+		})
+	}
+}
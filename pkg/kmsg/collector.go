@@ -0,0 +1,53 @@
+package kmsg
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exports the events counted by a Watcher
+type Collector struct {
+	watcher *Watcher
+
+	eventsTotal   *prometheus.Desc
+	lastEventTime *prometheus.Desc
+}
+
+func NewCollector(watcher *Watcher, namespace string) *Collector {
+	return &Collector{
+		watcher: watcher,
+		eventsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kernel", "events_total"),
+			"Total megaraid_sas kernel log events by host adapter and event type",
+			[]string{"host", "event"},
+			nil,
+		),
+		lastEventTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kernel", "last_event_timestamp_seconds"),
+			"Unix timestamp of the last megaraid_sas kernel log event by host adapter and event type",
+			[]string{"host", "event"},
+			nil,
+		),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.eventsTotal
+	ch <- c.lastEventTime
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, stat := range c.watcher.Events() {
+		ch <- prometheus.MustNewConstMetric(
+			c.eventsTotal,
+			prometheus.CounterValue,
+			float64(stat.Count),
+			stat.Host, stat.Event,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.lastEventTime,
+			prometheus.GaugeValue,
+			float64(stat.LastEvent.UnixNano())/1e9,
+			stat.Host, stat.Event,
+		)
+	}
+}
@@ -0,0 +1,379 @@
+package kmsg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+// DefaultPath is the kernel log device followed when no file is configured
+const DefaultPath = "/dev/kmsg"
+
+// Event types recognised in megaraid_sas kernel messages
+const (
+	EventFirmwareFault = "fw_fault"
+	EventOCR           = "ocr"
+	EventAdapterReset  = "adapter_reset"
+	EventAdapterKilled = "adapter_killed"
+	EventTaskAbort     = "task_abort"
+)
+
+// eventPatterns is evaluated in order, the first match wins. OCR (online
+// controller reset) lines usually also mention "reset", so they are matched
+// before plain adapter resets, and a failed reset ends with the adapter
+// being killed ("Reset failed, killing adapter scsi0.").
+var eventPatterns = []struct {
+	event   string
+	pattern *regexp.Regexp
+}{
+	{EventFirmwareFault, regexp.MustCompile(`(?i)fw (is )?in fault state`)},
+	{EventOCR, regexp.MustCompile(`(?i)\bocr\b|online controller reset`)},
+	{EventAdapterKilled, regexp.MustCompile(`(?i)kill(ing)? (the )?(adapter|hba)|adapter (was )?killed`)},
+	{EventTaskAbort, regexp.MustCompile(`(?i)task abort`)},
+	{EventAdapterReset, regexp.MustCompile(`(?i)resetting (fusion )?adapter|adapter reset`)},
+}
+
+// completionPattern matches the messages that end an event counted by its
+// first message, such as "Reset successful for scsi0." after "resetting
+// fusion adapter scsi0." and "task abort: SUCCESS" after "attempting task
+// abort!"
+var completionPattern = regexp.MustCompile(`(?i)reset successful|task abort: (success|failed)`)
+
+var (
+	pciAddressPattern = regexp.MustCompile(`\b[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9a-f]\b`)
+	scsiHostPattern   = regexp.MustCompile(`\bscsi(\d+)\b`)
+	scsiDevicePattern = regexp.MustCompile(`^(?:sd|scsi) (\d+):\d+:\d+:\d+:`)
+	// megaraid_sas registers its hosts as "scsi host0: Avago SAS based MegaRAID driver"
+	megaraidHostPattern = regexp.MustCompile(`^scsi host(\d+): .*MegaRAID`)
+	dmesgTimePattern    = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s*`)
+)
+
+// EventStat holds the counters for one host adapter and event type
+type EventStat struct {
+	Host      string    `json:"host"`
+	Event     string    `json:"event"`
+	Count     int       `json:"count"`
+	LastEvent time.Time `json:"last_event"`
+}
+
+type eventKey struct {
+	host  string
+	event string
+}
+
+// stateKey is the key the watcher keeps its counters under in the store
+const stateKey = "kmsg"
+
+// saved is what the watcher keeps in the store. /dev/kmsg replays the whole
+// ring buffer when it is opened, so the sequence number of the last counted
+// record is kept to avoid counting it again after a restart. Sequence
+// numbers start over when the machine boots.
+type saved struct {
+	BootID   string      `json:"boot_id"`
+	Sequence int64       `json:"sequence"`
+	Events   []EventStat `json:"events"`
+	// Hosts maps the SCSI host numbers of megaraid_sas adapters to their
+	// names, which hold until the next boot
+	Hosts map[string]string `json:"hosts,omitempty"`
+}
+
+// Watcher follows the kernel log and counts megaraid_sas events per host adapter
+type Watcher struct {
+	path         string
+	pollInterval time.Duration
+	bootTime     time.Time
+	bootID       string
+	store        *state.Store
+
+	mu       sync.Mutex
+	events   map[eventKey]*EventStat
+	hosts    map[string]string // SCSI host number to adapter, see saved.Hosts
+	sequence int64             // of the last counted record
+	skipTo   int64             // records up to this sequence were counted before a restart
+}
+
+// NewWatcher returns a watcher that continues counting from the state saved in store
+func NewWatcher(path string, store *state.Store) *Watcher {
+	if path == "" {
+		path = DefaultPath
+	}
+	w := &Watcher{
+		path:         path,
+		pollInterval: time.Second,
+		bootTime:     readBootTime(),
+		bootID:       readBootID(),
+		store:        store,
+		events:       make(map[eventKey]*EventStat),
+		hosts:        make(map[string]string),
+	}
+
+	var s saved
+	if ok, err := store.Get(stateKey, &s); err != nil {
+		log.Printf("WARNING: Discarding saved kernel log counters: %v", err)
+	} else if ok {
+		for i := range s.Events {
+			stat := s.Events[i]
+			w.events[eventKey{host: stat.Host, event: stat.Event}] = &stat
+		}
+		if s.BootID != "" && s.BootID == w.bootID {
+			w.sequence = s.Sequence
+			w.skipTo = s.Sequence
+			for number, host := range s.Hosts {
+				w.hosts[number] = host
+			}
+		}
+	}
+	return w
+}
+
+// Start opens the log and follows it in the background until ctx is cancelled
+func (w *Watcher) Start(ctx context.Context) error {
+	f, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	go w.follow(ctx, f)
+	return nil
+}
+
+func (w *Watcher) follow(ctx context.Context, f *os.File) {
+	reader := bufio.NewReaderSize(f, 8192)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			w.HandleLine(strings.TrimRight(line, "\n"))
+		}
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case errors.Is(err, io.EOF):
+			// Regular files are followed like tail -f
+			time.Sleep(w.pollInterval)
+		case errors.Is(err, syscall.EPIPE):
+			// /dev/kmsg returns EPIPE when records were overwritten before we read them
+			log.Printf("WARNING: kernel log records were lost while reading %s", w.path)
+		default:
+			log.Printf("ERROR: failed to read kernel log %s: %v", w.path, err)
+			return
+		}
+	}
+}
+
+// HandleLine parses one kernel log record and records it if it is a megaraid_sas
+// event, or a SCSI midlayer event on a host that megaraid_sas registered
+func (w *Watcher) HandleLine(line string) {
+	seq, hasSeq := recordSequence(line)
+	timestamp, message := w.splitRecord(line)
+
+	// Host registrations are learned from replayed records as well, as the
+	// events that follow them may not have been counted yet
+	w.mu.Lock()
+	host, ok := w.adapter(message)
+	w.mu.Unlock()
+	if !ok || hasSeq && seq <= w.skipTo || completionPattern.MatchString(message) {
+		return
+	}
+
+	event := ""
+	for _, p := range eventPatterns {
+		if p.pattern.MatchString(message) {
+			event = p.event
+			break
+		}
+	}
+	if event == "" {
+		return
+	}
+
+	key := eventKey{host: host, event: event}
+
+	w.mu.Lock()
+	stat, ok := w.events[key]
+	if !ok {
+		stat = &EventStat{Host: key.host, Event: key.event}
+		w.events[key] = stat
+	}
+	stat.Count++
+	if timestamp.After(stat.LastEvent) {
+		stat.LastEvent = timestamp
+	}
+	if hasSeq {
+		w.sequence = seq
+	}
+	sequence := w.sequence
+	w.mu.Unlock()
+
+	s := saved{BootID: w.bootID, Sequence: sequence, Events: w.Events(), Hosts: w.Hosts()}
+	if err := w.store.Put(stateKey, s); err != nil {
+		log.Printf("ERROR: Failed to save kernel log counters: %v", err)
+	}
+}
+
+// adapter returns the adapter a megaraid_sas message refers to, and learns
+// which SCSI host numbers belong to megaraid_sas. Messages of the SCSI
+// midlayer ("sd 0:2:0:0: attempting task abort!") are attributed to the
+// adapter of their host; other messages are not megaraid_sas events. w.mu
+// must be held.
+func (w *Watcher) adapter(message string) (string, bool) {
+	if match := megaraidHostPattern.FindStringSubmatch(message); match != nil {
+		if _, known := w.hosts[match[1]]; !known {
+			w.hosts[match[1]] = "host" + match[1]
+		}
+		return "", false
+	}
+
+	if strings.Contains(message, "megaraid_sas") || strings.Contains(message, "megasas") {
+		host := hostAdapter(message)
+		// Messages naming both the PCI address and the SCSI host tie the two
+		// together, so midlayer messages are counted under the same adapter
+		if match := scsiHostPattern.FindStringSubmatch(message); match != nil && pciAddressPattern.MatchString(message) {
+			w.hosts[match[1]] = host
+		}
+		return host, true
+	}
+
+	if match := scsiDevicePattern.FindStringSubmatch(message); match != nil {
+		host, ok := w.hosts[match[1]]
+		return host, ok
+	}
+	return "", false
+}
+
+// Hosts returns the SCSI host numbers known to belong to megaraid_sas, with
+// the adapter their events are counted under
+func (w *Watcher) Hosts() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hosts := make(map[string]string, len(w.hosts))
+	for number, host := range w.hosts {
+		hosts[number] = host
+	}
+	return hosts
+}
+
+// recordSequence returns the sequence number of a /dev/kmsg record
+// ("6,1234,5678901,-;message")
+func recordSequence(line string) (int64, bool) {
+	prefix, _, found := strings.Cut(line, ";")
+	if !found {
+		return 0, false
+	}
+	fields := strings.Split(prefix, ",")
+	if len(fields) < 3 {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(fields[1], 10, 64)
+	return seq, err == nil
+}
+
+// Events returns a copy of the current event counters, sorted by host and event
+func (w *Watcher) Events() []EventStat {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := make([]EventStat, 0, len(w.events))
+	for _, stat := range w.events {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Host != stats[j].Host {
+			return stats[i].Host < stats[j].Host
+		}
+		return stats[i].Event < stats[j].Event
+	})
+	return stats
+}
+
+// splitRecord separates the timestamp from the message. It understands the
+// /dev/kmsg record format ("6,1234,5678901,-;message") and dmesg output
+// ("[ 5.678901] message"). Lines without a timestamp are stamped with now.
+func (w *Watcher) splitRecord(line string) (time.Time, string) {
+	if prefix, message, found := strings.Cut(line, ";"); found {
+		fields := strings.Split(prefix, ",")
+		if len(fields) >= 3 {
+			if usec, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+				return w.sinceBoot(time.Duration(usec) * time.Microsecond), message
+			}
+		}
+	}
+
+	if match := dmesgTimePattern.FindStringSubmatch(line); match != nil {
+		if secs, err := strconv.ParseFloat(match[1], 64); err == nil {
+			return w.sinceBoot(time.Duration(secs * float64(time.Second))), line[len(match[0]):]
+		}
+	}
+
+	return time.Now(), line
+}
+
+func (w *Watcher) sinceBoot(offset time.Duration) time.Time {
+	if w.bootTime.IsZero() {
+		return time.Now()
+	}
+	return w.bootTime.Add(offset)
+}
+
+// hostAdapter identifies the adapter a message refers to, preferring the PCI
+// address megaraid_sas prefixes its messages with
+func hostAdapter(message string) string {
+	if addr := pciAddressPattern.FindString(message); addr != "" {
+		return addr
+	}
+	if match := scsiHostPattern.FindStringSubmatch(message); match != nil {
+		return "host" + match[1]
+	}
+	if match := scsiDevicePattern.FindStringSubmatch(message); match != nil {
+		return "host" + match[1]
+	}
+	return "unknown"
+}
+
+// readBootID returns the random ID the kernel generates at every boot
+func readBootID() string {
+	data, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readBootTime derives the boot time from /proc/uptime
+func readBootTime() time.Time {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return time.Time{}
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return time.Time{}
+	}
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Now().Add(-time.Duration(uptime * float64(time.Second)))
+}
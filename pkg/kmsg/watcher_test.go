@@ -0,0 +1,127 @@
+package kmsg
+
+import (
+	"testing"
+
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+func TestHandleLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  map[eventKey]int
+	}{
+		{
+			name: "reset counted once",
+			lines: []string{
+				"4,10,3000000,-;megaraid_sas 0000:02:00.0: resetting fusion adapter scsi0.",
+				"4,11,3000100,-;megaraid_sas 0000:02:00.0: Reset successful for scsi0.",
+			},
+			want: map[eventKey]int{{"0000:02:00.0", EventAdapterReset}: 1},
+		},
+		{
+			name: "failed reset kills the adapter",
+			lines: []string{
+				"4,10,3000000,-;megaraid_sas 0000:02:00.0: resetting fusion adapter scsi0.",
+				"3,11,3000100,-;megaraid_sas 0000:02:00.0: Reset failed, killing adapter scsi0.",
+			},
+			want: map[eventKey]int{
+				{"0000:02:00.0", EventAdapterReset}:  1,
+				{"0000:02:00.0", EventAdapterKilled}: 1,
+			},
+		},
+		{
+			name: "firmware fault and OCR",
+			lines: []string{
+				"[ 12.345678] megaraid_sas 0000:02:00.0: FW in FAULT state!!",
+				"[ 12.345679] megaraid_sas 0000:02:00.0: Initiating OCR",
+			},
+			want: map[eventKey]int{
+				{"0000:02:00.0", EventFirmwareFault}: 1,
+				{"0000:02:00.0", EventOCR}:           1,
+			},
+		},
+		{
+			name: "task abort on a megaraid_sas host",
+			lines: []string{
+				"6,1,1000000,-;scsi host0: Avago SAS based MegaRAID driver",
+				"4,2,2000000,-;sd 0:2:0:0: attempting task abort! scmd(00000000abcd)",
+				"4,3,2000100,-;sd 0:2:0:0: task abort: SUCCESS scmd(00000000abcd)",
+			},
+			want: map[eventKey]int{{"host0", EventTaskAbort}: 1},
+		},
+		{
+			name: "task abort counted under the PCI address of its host",
+			lines: []string{
+				"6,1,1000000,-;scsi host0: Avago SAS based MegaRAID driver",
+				"6,2,1000100,-;megaraid_sas 0000:02:00.0: FW now in Ready state scsi0",
+				"4,3,2000000,-;sd 0:2:0:0: attempting task abort! scmd(00000000abcd)",
+			},
+			want: map[eventKey]int{{"0000:02:00.0", EventTaskAbort}: 1},
+		},
+		{
+			name: "task abort on another driver's host",
+			lines: []string{
+				"6,1,1000000,-;scsi host0: Avago SAS based MegaRAID driver",
+				"4,2,2000000,-;sd 1:0:0:0: attempting task abort! scmd(00000000ef01)",
+			},
+			want: map[eventKey]int{},
+		},
+		{
+			name: "other drivers",
+			lines: []string{
+				"4,1,1000000,-;mpt3sas_cm0: resetting adapter",
+				"4,2,1000100,-;e1000e 0000:00:1f.6 eth0: Reset adapter unexpectedly",
+			},
+			want: map[eventKey]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.Open("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := NewWatcher("", store)
+			for _, line := range tt.lines {
+				w.HandleLine(line)
+			}
+
+			got := make(map[eventKey]int)
+			for _, stat := range w.Events() {
+				got[eventKey{host: stat.Host, event: stat.Event}] = stat.Count
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got events %v, want %v", got, tt.want)
+			}
+			for key, count := range tt.want {
+				if got[key] != count {
+					t.Errorf("%s/%s: got %d, want %d", key.host, key.event, got[key], count)
+				}
+			}
+		})
+	}
+}
+
+func TestHostsSurviveRestart(t *testing.T) {
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher("", store)
+	if w.bootID == "" {
+		t.Skip("no boot ID on this system")
+	}
+	w.HandleLine("6,1,1000000,-;scsi host0: Avago SAS based MegaRAID driver")
+	w.HandleLine("4,2,2000000,-;sd 0:2:0:0: attempting task abort! scmd(00000000abcd)")
+
+	// The registration is no longer in the ring buffer after the restart
+	restarted := NewWatcher("", store)
+	restarted.HandleLine("4,3,3000000,-;sd 0:2:0:0: attempting task abort! scmd(00000000abcd)")
+	events := restarted.Events()
+	if len(events) != 1 || events[0].Host != "host0" || events[0].Count != 2 {
+		t.Errorf("unexpected events after restart: %+v", events)
+	}
+}
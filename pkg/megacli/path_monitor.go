@@ -5,7 +5,7 @@ import (
 	"log"
 	"time"
 	
-	"github.com/manojsiriparthi/megaraid-exporter/config"
+	"github.com/yourusername/megaraid-exporter/config"
 )
 
 type PathMonitor struct {
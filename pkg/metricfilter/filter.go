@@ -0,0 +1,86 @@
+package metricfilter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Filter decides which metric families are exported by name
+type Filter struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// New compiles the allow and deny expressions, which must match the whole
+// metric name. An empty allow list allows every metric; deny always wins
+// over allow.
+func New(allow, deny []string) (*Filter, error) {
+	f := &Filter{}
+
+	for _, expr := range allow {
+		re, err := compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow pattern %q: %v", expr, err)
+		}
+		f.allow = append(f.allow, re)
+	}
+	for _, expr := range deny {
+		re, err := compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %v", expr, err)
+		}
+		f.deny = append(f.deny, re)
+	}
+
+	return f, nil
+}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// Match reports whether a metric with the given name should be exported
+func (f *Filter) Match(name string) bool {
+	for _, re := range f.deny {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, re := range f.allow {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the matching metric families
+func (f *Filter) Apply(families []*dto.MetricFamily) []*dto.MetricFamily {
+	if len(f.allow) == 0 && len(f.deny) == 0 {
+		return families
+	}
+	filtered := families[:0]
+	for _, mf := range families {
+		if f.Match(mf.GetName()) {
+			filtered = append(filtered, mf)
+		}
+	}
+	return filtered
+}
+
+// Gatherer wraps g so that only matching metric families are returned
+func (f *Filter) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	if len(f.allow) == 0 && len(f.deny) == 0 {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		return f.Apply(families), err
+	})
+}
@@ -0,0 +1,129 @@
+package metricfilter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		want  map[string]bool
+	}{
+		{
+			name: "no patterns",
+			want: map[string]bool{"megaraid_pd_state": true, "go_goroutines": true},
+		},
+		{
+			name:  "allow matches whole names",
+			allow: []string{"megaraid_pd_state"},
+			want: map[string]bool{
+				"megaraid_pd_state":                               true,
+				"megaraid_pd_state_info":                          false,
+				"megaraid_pd_state_last_change_timestamp_seconds": false,
+				"x_megaraid_pd_state":                             false,
+			},
+		},
+		{
+			name:  "allow prefix",
+			allow: []string{"megaraid_pd_.*", "megaraid_vd_status"},
+			want: map[string]bool{
+				"megaraid_pd_state":        true,
+				"megaraid_pd_info":         true,
+				"megaraid_vd_status":       true,
+				"megaraid_vd_state":        false,
+				"megaraid_controller_info": false,
+			},
+		},
+		{
+			name: "deny matches whole names",
+			deny: []string{"megaraid_pd_info"},
+			want: map[string]bool{
+				"megaraid_pd_info":       false,
+				"megaraid_pd_info_total": true,
+				"megaraid_pd_state":      true,
+			},
+		},
+		{
+			name: "alternation is anchored as a whole",
+			deny: []string{"megaraid_pd_info|megaraid_vd_info"},
+			want: map[string]bool{
+				"megaraid_pd_info":   false,
+				"megaraid_vd_info":   false,
+				"megaraid_pd_info_x": true,
+				"x_megaraid_vd_info": true,
+			},
+		},
+		{
+			name:  "explicit anchors still work",
+			allow: []string{"^megaraid_.*$"},
+			want:  map[string]bool{"megaraid_pd_state": true, "go_goroutines": false},
+		},
+		{
+			name:  "deny wins",
+			allow: []string{"megaraid_.*"},
+			deny:  []string{"megaraid_.*_info"},
+			want: map[string]bool{
+				"megaraid_pd_state": true,
+				"megaraid_pd_info":  false,
+				"go_goroutines":     false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := f.Match(name); got != want {
+					t.Errorf("Match(%q) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New([]string{"megaraid_("}, nil); err == nil {
+		t.Error("expected an error for an invalid allow pattern")
+	}
+	if _, err := New(nil, []string{"[megaraid"}); err == nil {
+		t.Error("expected an error for an invalid deny pattern")
+	}
+}
+
+func TestGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"megaraid_pd_state", "megaraid_pd_state_info", "megaraid_vd_state"} {
+		registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: name}))
+	}
+	f, err := New([]string{"megaraid_pd_state", "megaraid_vd_.*"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := f.Gatherer(registry).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, mf := range families {
+		names = append(names, mf.GetName())
+	}
+	if len(names) != 2 || names[0] != "megaraid_pd_state" || names[1] != "megaraid_vd_state" {
+		t.Errorf("got %v, want megaraid_pd_state and megaraid_vd_state", names)
+	}
+
+	// Without patterns the families are returned as they are
+	all := []*dto.MetricFamily{{}, {}}
+	if empty, _ := New(nil, nil); len(empty.Apply(all)) != 2 {
+		t.Error("a filter without patterns dropped metric families")
+	}
+}
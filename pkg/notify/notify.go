@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+// stateKey is the key the notifier keeps its state under in the store
+const stateKey = "notify"
+
+// saved is what the notifier keeps in the store, so that changes made while
+// the exporter was down are reported and repeats are still suppressed
+type saved struct {
+	Previous *diskutil.Snapshot   `json:"previous"`
+	Sent     map[string]time.Time `json:"sent"`
+}
+
+// Config mirrors the notifications section of config.yaml
+type Config struct {
+	// MinSeverity drops changes below ok, warning or critical
+	MinSeverity string `yaml:"min_severity"`
+	// DedupWindow suppresses a repeat of the same change to the same
+	// component within this time, e.g. a drive flapping offline
+	DedupWindow time.Duration `yaml:"dedup_window"`
+	// Retries is how often a failed delivery is retried, RetryDelay the
+	// wait before the first retry. The wait doubles with every retry.
+	Retries    int           `yaml:"retries"`
+	RetryDelay time.Duration `yaml:"retry_delay"`
+	// Timeout limits each delivery attempt
+	Timeout time.Duration `yaml:"timeout"`
+
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	Slack    []SlackConfig   `yaml:"slack"`
+	SMTP     []SMTPConfig    `yaml:"smtp"`
+}
+
+// Enabled reports whether any sink is configured
+func (c Config) Enabled() bool {
+	return len(c.Webhooks)+len(c.Slack)+len(c.SMTP) > 0
+}
+
+// Event is one change between consecutive snapshots
+type Event struct {
+	Time time.Time `json:"time"`
+	Host string    `json:"host"`
+	diskutil.Change
+}
+
+// Sink delivers events somewhere
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, events []Event) error
+}
+
+// Notifier compares each snapshot with the previous one and sends the
+// changes to every sink
+type Notifier struct {
+	sinks       []Sink
+	host        string
+	minSeverity diskutil.Severity
+	dedupWindow time.Duration
+	retries     int
+	retryDelay  time.Duration
+	timeout     time.Duration
+	queue       chan []Event
+	store       *state.Store
+
+	mu       sync.Mutex
+	previous *diskutil.Snapshot
+	sent     map[string]time.Time
+}
+
+// New returns a notifier that compares the first snapshot with the last one
+// saved in store
+func New(cfg Config, host string, store *state.Store) (*Notifier, error) {
+	minSeverity, err := diskutil.ParseSeverity(cfg.MinSeverity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notifications.min_severity: %v", err)
+	}
+
+	var sinks []Sink
+	for _, c := range cfg.Webhooks {
+		sink, err := NewWebhook(c)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, c := range cfg.Slack {
+		sink, err := NewSlack(c)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, c := range cfg.SMTP {
+		sink, err := NewSMTP(c)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	n := &Notifier{
+		sinks:       sinks,
+		host:        host,
+		minSeverity: minSeverity,
+		dedupWindow: cfg.DedupWindow,
+		retries:     cfg.Retries,
+		retryDelay:  cfg.RetryDelay,
+		timeout:     cfg.Timeout,
+		queue:       make(chan []Event, 16),
+		store:       store,
+		sent:        make(map[string]time.Time),
+	}
+
+	var st saved
+	if ok, err := store.Get(stateKey, &st); err != nil {
+		log.Printf("WARNING: Discarding saved notification state: %v", err)
+	} else if ok {
+		n.previous = st.Previous
+		if st.Sent != nil {
+			n.sent = st.Sent
+		}
+	}
+	return n, nil
+}
+
+// Start delivers queued events until ctx is cancelled, so that slow or
+// retried deliveries do not hold up collection
+func (n *Notifier) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case events := <-n.queue:
+				for _, sink := range n.sinks {
+					n.deliver(ctx, sink, events)
+				}
+			}
+		}
+	}()
+}
+
+// Observe compares s with the previous snapshot and queues the changes.
+// Sections that failed to collect keep their previous contents, otherwise
+// every drive in a section that failed once would be reported as removed and
+// added again. The other sections are compared as usual.
+func (n *Notifier) Observe(s *diskutil.Snapshot) {
+	n.mu.Lock()
+	previous := n.previous
+	current := merge(previous, s)
+	n.previous = current
+	if previous == nil {
+		n.mu.Unlock()
+		n.save()
+		return
+	}
+	events := n.filter(diskutil.Diff(baseline(previous, current), current), s.CollectedAt)
+	n.mu.Unlock()
+	n.save()
+
+	if len(events) == 0 {
+		return
+	}
+	select {
+	case n.queue <- events:
+	default:
+		log.Printf("WARNING: notification queue full, dropping %d events", len(events))
+	}
+}
+
+// merge returns s with its failed sections taken from previous. Sections
+// that have not been collected yet stay failed.
+func merge(previous, s *diskutil.Snapshot) *diskutil.Snapshot {
+	merged := *s
+	merged.Errors = make(map[string]string)
+	for section, msg := range s.Errors {
+		if previous != nil {
+			if _, failed := previous.Errors[section]; !failed {
+				copySection(&merged, previous, section)
+				continue
+			}
+		}
+		merged.Errors[section] = msg
+	}
+	return &merged
+}
+
+// baseline returns previous with the sections it never collected taken from
+// current, so that their first collection is not reported as new components
+func baseline(previous, current *diskutil.Snapshot) *diskutil.Snapshot {
+	before := *previous
+	for section := range previous.Errors {
+		copySection(&before, current, section)
+	}
+	return &before
+}
+
+// copySection sets section of dst to the one in src
+func copySection(dst, src *diskutil.Snapshot, section string) {
+	switch section {
+	case backend.SectionController:
+		dst.Controllers = src.Controllers
+	case backend.SectionVD:
+		dst.VirtualDrives = src.VirtualDrives
+	case backend.SectionPD:
+		dst.PhysicalDrives = src.PhysicalDrives
+	case backend.SectionBBU:
+		dst.Batteries = src.Batteries
+	}
+}
+
+func (n *Notifier) save() {
+	n.mu.Lock()
+	st := saved{Previous: n.previous, Sent: make(map[string]time.Time, len(n.sent))}
+	for key, at := range n.sent {
+		st.Sent[key] = at
+	}
+	n.mu.Unlock()
+
+	if err := n.store.Put(stateKey, st); err != nil {
+		log.Printf("ERROR: Failed to save notification state: %v", err)
+	}
+}
+
+// filter drops changes below the minimum severity and repeats within the
+// dedup window. n.mu must be held.
+func (n *Notifier) filter(changes []diskutil.Change, now time.Time) []Event {
+	for key, at := range n.sent {
+		if now.Sub(at) >= n.dedupWindow {
+			delete(n.sent, key)
+		}
+	}
+
+	var events []Event
+	for _, change := range changes {
+		if change.Severity < n.minSeverity {
+			continue
+		}
+		key := strings.Join([]string{change.Kind, change.Component, change.New}, "|")
+		if _, seen := n.sent[key]; seen {
+			continue
+		}
+		n.sent[key] = now
+		events = append(events, Event{Time: now, Host: n.host, Change: change})
+	}
+	return events
+}
+
+// deliver sends events to sink, retrying with exponential backoff
+func (n *Notifier) deliver(ctx context.Context, sink Sink, events []Event) {
+	delay := n.retryDelay
+	for attempt := 0; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, n.timeout)
+		err := sink.Send(sendCtx, events)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= n.retries {
+			log.Printf("ERROR: Failed to send %d events to %s after %d attempts: %v", len(events), sink.Name(), attempt+1, err)
+			return
+		}
+		log.Printf("WARNING: Failed to send events to %s, retrying in %s: %v", sink.Name(), delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// summary is the one-line subject of a batch of events
+func summary(events []Event) string {
+	severity := diskutil.SeverityOK
+	for _, e := range events {
+		if e.Severity > severity {
+			severity = e.Severity
+		}
+	}
+	if len(events) == 1 {
+		return fmt.Sprintf("[%s] %s: %s %s", severity, events[0].Host, events[0].Component, events[0].Message)
+	}
+	return fmt.Sprintf("[%s] %s: %d MegaRAID changes", severity, events[0].Host, len(events))
+}
+
+// formatEvent is the plain text line for one event
+func formatEvent(e Event) string {
+	return fmt.Sprintf("%s %s %s: %s", e.Time.Format(time.RFC3339), e.Severity, e.Component, e.Message)
+}
@@ -0,0 +1,322 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+// recorder is a webhook endpoint that fails the first failures requests
+type recorder struct {
+	failures int
+
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	if len(r.bodies) <= r.failures {
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+	}
+}
+
+func (r *recorder) requests() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bodies
+}
+
+func newNotifier(t *testing.T, cfg Config) *Notifier {
+	t.Helper()
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(cfg, "host1", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func snapshotAt(at time.Time, pdState string) *diskutil.Snapshot {
+	s := diskutil.NewSnapshot("storcli")
+	s.CollectedAt = at
+	s.PhysicalDrives = []*diskutil.PhysicalDriveStat{
+		{EnclosureDeviceId: 32, SlotNumber: 1, FirmwareState: pdState, SerialNumber: "Z1Z0AAAA"},
+	}
+	return s
+}
+
+var testEvents = []Event{{
+	Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Host: "host1",
+	Change: diskutil.Change{
+		Kind:      diskutil.ChangePDState,
+		Severity:  diskutil.SeverityCritical,
+		Component: "pd 0/32:1",
+		Message:   "state changed from online to offline",
+		Old:       "online",
+		New:       "offline",
+	},
+}}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		requests int
+	}{
+		{"first attempt", 0, 2, 1},
+		{"succeeds on retry", 2, 2, 3},
+		{"gives up", 5, 2, 3},
+		{"no retries", 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := &recorder{failures: tt.failures}
+			server := httptest.NewServer(endpoint)
+			defer server.Close()
+
+			n := newNotifier(t, Config{
+				Retries:    tt.retries,
+				RetryDelay: time.Millisecond,
+				Webhooks:   []WebhookConfig{{URL: server.URL, Headers: map[string]string{"X-Token": "secret"}}},
+			})
+			n.deliver(context.Background(), n.sinks[0], testEvents)
+
+			requests := endpoint.requests()
+			if len(requests) != tt.requests {
+				t.Fatalf("got %d requests, want %d", len(requests), tt.requests)
+			}
+			var payload struct {
+				Events []struct {
+					Severity  string `json:"severity"`
+					Component string `json:"component"`
+					New       string `json:"new"`
+				} `json:"events"`
+			}
+			if err := json.Unmarshal(requests[len(requests)-1], &payload); err != nil {
+				t.Fatalf("invalid payload: %v", err)
+			}
+			if len(payload.Events) != 1 || payload.Events[0].Severity != "critical" ||
+				payload.Events[0].Component != "pd 0/32:1" || payload.Events[0].New != "offline" {
+				t.Errorf("unexpected events: %+v", payload.Events)
+			}
+		})
+	}
+}
+
+func TestWebhookHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhook(WebhookConfig{URL: server.URL, Headers: map[string]string{"X-Token": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(context.Background(), testEvents); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("X-Token") != "secret" || got.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers: %v", got)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	endpoint := &recorder{failures: 1}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	n := newNotifier(t, Config{
+		Retries:    1,
+		RetryDelay: time.Millisecond,
+		Slack:      []SlackConfig{{WebhookURL: server.URL, Channel: "#storage", Username: "megaraid"}},
+	})
+	n.deliver(context.Background(), n.sinks[0], testEvents)
+
+	requests := endpoint.requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(requests[1], &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload["channel"] != "#storage" || payload["username"] != "megaraid" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+	for _, want := range []string{"*[critical] host1: pd 0/32:1 state changed from online to offline*", ":red_circle: `pd 0/32:1`"} {
+		if !strings.Contains(payload["text"], want) {
+			t.Errorf("text %q does not contain %q", payload["text"], want)
+		}
+	}
+}
+
+func TestDedup(t *testing.T) {
+	n := newNotifier(t, Config{DedupWindow: time.Hour, Webhooks: []WebhookConfig{{URL: "http://localhost"}}})
+	start := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name   string
+		after  time.Duration
+		state  string
+		failed bool
+		events int
+	}{
+		{"first snapshot", 0, "Onln", false, 0},
+		{"goes offline", time.Minute, "Offln", false, 1},
+		{"comes back", 2 * time.Minute, "Onln", false, 1},
+		{"offline again within the window", 3 * time.Minute, "Offln", false, 0},
+		{"failed collection is skipped", 4 * time.Minute, "Onln", true, 0},
+		{"back online within the window", 5 * time.Minute, "Onln", false, 0},
+		{"offline after the window", 2 * time.Hour, "Offln", false, 1},
+	}
+	for _, step := range steps {
+		s := snapshotAt(start.Add(step.after), step.state)
+		if step.failed {
+			s.SetError("pd", context.DeadlineExceeded)
+		}
+		n.Observe(s)
+
+		var events []Event
+		select {
+		case events = <-n.queue:
+		default:
+		}
+		if len(events) != step.events {
+			t.Errorf("%s: got %d events, want %d: %+v", step.name, len(events), step.events, events)
+		}
+	}
+}
+
+func TestObserveFailedSections(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	// step is one collection, "" means the section failed
+	type step struct {
+		pd, bbu string
+		kinds   []string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "other sections are compared",
+			steps: []step{
+				{pd: "Onln", bbu: "Optimal"},
+				{pd: "", bbu: "Degraded", kinds: []string{diskutil.ChangeBBUState}},
+			},
+		},
+		{
+			name: "failed section is not reported as removed and added",
+			steps: []step{
+				{pd: "Onln", bbu: "Optimal"},
+				{pd: "", bbu: "Optimal"},
+				{pd: "Onln", bbu: "Optimal"},
+			},
+		},
+		{
+			name: "change during a failure is reported afterwards",
+			steps: []step{
+				{pd: "Onln", bbu: "Optimal"},
+				{pd: "", bbu: "Optimal"},
+				{pd: "", bbu: "Optimal"},
+				{pd: "Offln", bbu: "Optimal", kinds: []string{diskutil.ChangePDState}},
+			},
+		},
+		{
+			name: "section failing from the start",
+			steps: []step{
+				{pd: "", bbu: "Optimal"},
+				{pd: "", bbu: "Degraded", kinds: []string{diskutil.ChangeBBUState}},
+				{pd: "Onln", bbu: "Degraded"},
+				{pd: "Offln", bbu: "Degraded", kinds: []string{diskutil.ChangePDState}},
+			},
+		},
+		{
+			name: "everything failed",
+			steps: []step{
+				{pd: "Onln", bbu: "Optimal"},
+				{pd: "", bbu: ""},
+				{pd: "Onln", bbu: "Optimal"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newNotifier(t, Config{Webhooks: []WebhookConfig{{URL: "http://localhost"}}})
+			for i, step := range tt.steps {
+				s := snapshotAt(start.Add(time.Duration(i)*time.Minute), step.pd)
+				if step.pd == "" {
+					s.PhysicalDrives = nil
+					s.SetError("pd", context.DeadlineExceeded)
+				}
+				if step.bbu == "" {
+					s.SetError("bbu", context.DeadlineExceeded)
+				} else {
+					s.Batteries = []*diskutil.BatteryBackupStat{{BatteryType: "CVPM02", BatteryState: step.bbu}}
+				}
+				n.Observe(s)
+
+				var kinds []string
+				select {
+				case events := <-n.queue:
+					for _, e := range events {
+						kinds = append(kinds, e.Kind)
+					}
+				default:
+				}
+				if strings.Join(kinds, ",") != strings.Join(step.kinds, ",") {
+					t.Errorf("step %d: got changes %v, want %v", i, kinds, step.kinds)
+				}
+			}
+		})
+	}
+}
+
+func TestMinSeverity(t *testing.T) {
+	n := newNotifier(t, Config{MinSeverity: "critical", Webhooks: []WebhookConfig{{URL: "http://localhost"}}})
+	start := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	n.Observe(snapshotAt(start, "Offln"))
+	n.Observe(snapshotAt(start.Add(time.Minute), "Onln"))
+	select {
+	case events := <-n.queue:
+		t.Errorf("drive coming online is below critical: %+v", events)
+	default:
+	}
+
+	n.Observe(snapshotAt(start.Add(2*time.Minute), "Offln"))
+	select {
+	case events := <-n.queue:
+		if len(events) != 1 || events[0].Severity != diskutil.SeverityCritical {
+			t.Errorf("unexpected events: %+v", events)
+		}
+	default:
+		t.Error("drive going offline was not sent")
+	}
+}
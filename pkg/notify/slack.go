@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+// SlackConfig describes a Slack incoming webhook, or any chat service that
+// accepts the same payload (Mattermost, Rocket.Chat)
+type SlackConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+	Channel    string `yaml:"channel"`
+	Username   string `yaml:"username"`
+}
+
+// Slack posts one message per batch of events
+type Slack struct {
+	cfg    SlackConfig
+	client *http.Client
+}
+
+func NewSlack(cfg SlackConfig) (*Slack, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("slack webhook_url is required")
+	}
+	return &Slack{cfg: cfg, client: http.DefaultClient}, nil
+}
+
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+var slackIcons = map[diskutil.Severity]string{
+	diskutil.SeverityOK:       ":large_green_circle:",
+	diskutil.SeverityWarning:  ":large_orange_circle:",
+	diskutil.SeverityCritical: ":red_circle:",
+}
+
+func (s *Slack) Send(ctx context.Context, events []Event) error {
+	lines := []string{"*" + summary(events) + "*"}
+	for _, e := range events {
+		lines = append(lines, fmt.Sprintf("%s `%s` %s", slackIcons[e.Severity], e.Component, e.Message))
+	}
+
+	payload := map[string]string{"text": strings.Join(lines, "\n")}
+	if s.cfg.Channel != "" {
+		payload["channel"] = s.cfg.Channel
+	}
+	if s.cfg.Username != "" {
+		payload["username"] = s.cfg.Username
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.cfg.WebhookURL, nil, body)
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig describes a mail relay and the recipients
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// SMTP mails one message per batch of events. STARTTLS is used when the
+// relay offers it; credentials are only sent over TLS or to localhost.
+type SMTP struct {
+	cfg  SMTPConfig
+	addr string
+}
+
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("smtp host, from and to are required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 25
+	}
+	return &SMTP{cfg: cfg, addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))}, nil
+}
+
+func (s *SMTP) Name() string {
+	return "smtp " + s.addr
+}
+
+func (s *SMTP) Send(ctx context.Context, events []Event) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", summary(events))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, e := range events {
+		body.WriteString(formatEvent(e) + "\r\n")
+	}
+
+	// net/smtp has no context support, so give up waiting when ctx ends
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, auth, s.cfg.From, s.cfg.To, []byte(body.String()))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one message without TLS or authentication and sends
+// the envelope and data on the returned channel
+func fakeSMTP(t *testing.T) (host string, port int, messages <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		var message strings.Builder
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.Fields(line + " ")[0])
+			switch command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				message.WriteString(line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					message.WriteString(line)
+				}
+				reply("250 OK")
+				ch <- message.String()
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestSMTP(t *testing.T) {
+	host, port, messages := fakeSMTP(t)
+	sink, err := NewSMTP(SMTPConfig{
+		Host: host,
+		Port: port,
+		From: "megaraid@example.com",
+		To:   []string{"ops@example.com", "storage@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink.Name() != "smtp "+net.JoinHostPort(host, strconv.Itoa(port)) {
+		t.Errorf("unexpected name %q", sink.Name())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sink.Send(ctx, testEvents); err != nil {
+		t.Fatal(err)
+	}
+
+	var message string
+	select {
+	case message = <-messages:
+	case <-ctx.Done():
+		t.Fatal("no message received")
+	}
+	for _, want := range []string{
+		"MAIL FROM:<megaraid@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<storage@example.com>",
+		"To: ops@example.com, storage@example.com\r\n",
+		"Subject: [critical] host1: pd 0/32:1 state changed from online to offline\r\n",
+		"2026-01-02T03:04:05Z critical pd 0/32:1: state changed from online to offline\r\n",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message does not contain %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestSMTPConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  SMTPConfig
+		err  bool
+		addr string
+	}{
+		{"default port", SMTPConfig{Host: "mail", From: "a@example.com", To: []string{"b@example.com"}}, false, "mail:25"},
+		{"port", SMTPConfig{Host: "mail", Port: 587, From: "a@example.com", To: []string{"b@example.com"}}, false, "mail:587"},
+		{"no host", SMTPConfig{From: "a@example.com", To: []string{"b@example.com"}}, true, ""},
+		{"no recipients", SMTPConfig{Host: "mail", From: "a@example.com"}, true, ""},
+	}
+	for _, tt := range tests {
+		sink, err := NewSMTP(tt.cfg)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && sink.addr != tt.addr {
+			t.Errorf("%s: got address %s, want %s", tt.name, sink.addr, tt.addr)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookConfig describes a generic JSON webhook
+type WebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Webhook posts {"events": [...]} as JSON
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhook(cfg WebhookConfig) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	return &Webhook{url: cfg.URL, headers: cfg.Headers, client: http.DefaultClient}, nil
+}
+
+func (w *Webhook) Name() string {
+	return "webhook " + w.url
+}
+
+func (w *Webhook) Send(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.client, w.url, w.headers, body)
+}
+
+// post sends a JSON body and treats any non-2xx response as an error
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg = bytes.TrimSpace(msg); len(msg) > 0 {
+			return fmt.Errorf("unexpected status %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
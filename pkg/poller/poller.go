@@ -0,0 +1,136 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+// CollectorStatus is the outcome of the last collection of one section
+type CollectorStatus struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Status describes the last background collection
+type Status struct {
+	LastCollection time.Time                  `json:"last_collection"`
+	Duration       time.Duration              `json:"-"`
+	Collectors     map[string]CollectorStatus `json:"collectors"`
+}
+
+// Succeeded reports whether every section of the last collection succeeded
+func (s Status) Succeeded() bool {
+	if s.LastCollection.IsZero() {
+		return false
+	}
+	for _, collector := range s.Collectors {
+		if !collector.Success {
+			return false
+		}
+	}
+	return true
+}
+
+// Poller collects a snapshot in the background every interval, for readiness
+// checks and for consumers that must not run the CLI tool on every request
+type Poller struct {
+	backend  backend.Backend
+	sections []string
+	interval time.Duration
+
+	// observers are called with every new snapshot
+	observers []func(*diskutil.Snapshot)
+
+	mu       sync.RWMutex
+	snapshot *diskutil.Snapshot
+	status   Status
+}
+
+// New returns a poller that collects sections from b every interval
+func New(b backend.Backend, sections []string, interval time.Duration) (*Poller, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid collection interval %s, must be above 0", interval)
+	}
+	return &Poller{
+		backend:  b,
+		sections: sections,
+		interval: interval,
+	}, nil
+}
+
+// Interval returns the time between collections
+func (p *Poller) Interval() time.Duration {
+	return p.interval
+}
+
+// OnCollect registers fn to be called after every collection, in the
+// collecting goroutine. Register observers before calling Start.
+func (p *Poller) OnCollect(fn func(*diskutil.Snapshot)) {
+	p.observers = append(p.observers, fn)
+}
+
+// Start collects once right away and then every interval until ctx is cancelled
+func (p *Poller) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.Collect()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Collect runs one collection and stores the result
+func (p *Poller) Collect() *diskutil.Snapshot {
+	start := time.Now()
+	snapshot := p.backend.Collect(p.sections)
+
+	status := Status{
+		LastCollection: snapshot.CollectedAt,
+		Duration:       time.Since(start),
+		Collectors:     make(map[string]CollectorStatus),
+	}
+	for _, section := range p.sections {
+		msg, failed := snapshot.Errors[section]
+		status.Collectors[section] = CollectorStatus{Success: !failed, Error: msg}
+		if failed {
+			log.Printf("WARNING: background collection of %s failed: %s", section, msg)
+		}
+	}
+
+	p.mu.Lock()
+	p.snapshot = snapshot
+	p.status = status
+	p.mu.Unlock()
+
+	for _, observe := range p.observers {
+		observe(snapshot)
+	}
+	return snapshot
+}
+
+// Snapshot returns the last collected snapshot, or nil before the first collection
+func (p *Poller) Snapshot() *diskutil.Snapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.snapshot
+}
+
+// Status returns the outcome of the last collection
+func (p *Poller) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
@@ -0,0 +1,23 @@
+package poller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		err      bool
+	}{
+		{30 * time.Second, false},
+		{time.Millisecond, false},
+		{0, true},
+		{-time.Second, true},
+	}
+	for _, tt := range tests {
+		if _, err := New(nil, nil, tt.interval); (err != nil) != tt.err {
+			t.Errorf("interval %s: got error %v, want error %v", tt.interval, err, tt.err)
+		}
+	}
+}
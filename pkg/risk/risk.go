@@ -0,0 +1,331 @@
+package risk
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+// stateKey is the key the scorer keeps its per-drive memory under in the store
+const stateKey = "risk"
+
+// Factor names, also used as the keys of Config.Weights
+const (
+	FactorMediaErrors        = "media_errors"
+	FactorOtherErrors        = "other_errors"
+	FactorPredictiveFailures = "predictive_failures"
+	FactorSMARTAlert         = "smart_alert"
+	FactorErrorRate          = "error_rate"
+	FactorTemperature        = "temperature_excursions"
+	// FactorFirstSeen is the time since the exporter first saw the drive.
+	// Neither backend reports power-on hours, so this is not the drive's age.
+	FactorFirstSeen = "time_since_first_seen"
+)
+
+// Config mirrors the risk section of config.yaml
+type Config struct {
+	// Weights scale each factor; the score is the weighted average of the
+	// factors, from 0 (no sign of trouble) to 100
+	Weights map[string]float64 `yaml:"weights"`
+	// RateWindow is the period the error rate is measured over
+	RateWindow time.Duration `yaml:"rate_window"`
+	// TemperatureLimit is the drive temperature (Celsius) above which a
+	// reading counts as an excursion
+	TemperatureLimit float64 `yaml:"temperature_limit"`
+	// ExpectedLifetime is the time since first seen at which that factor
+	// reaches 1
+	ExpectedLifetime time.Duration `yaml:"expected_lifetime"`
+}
+
+// DefaultWeights favours the counters that predict failures best
+var DefaultWeights = map[string]float64{
+	FactorMediaErrors:        25,
+	FactorOtherErrors:        5,
+	FactorPredictiveFailures: 25,
+	FactorSMARTAlert:         20,
+	FactorErrorRate:          15,
+	FactorTemperature:        5,
+	FactorFirstSeen:          5,
+}
+
+// halfPoints is the raw value at which a counting factor reaches 0.5; the
+// factors saturate towards 1 above it
+var halfPoints = map[string]float64{
+	FactorMediaErrors:        10,
+	FactorOtherErrors:        50,
+	FactorPredictiveFailures: 1,
+	FactorErrorRate:          5, // errors per day
+	FactorTemperature:        3,
+}
+
+// Factor is one input to a drive's score
+type Factor struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+	Detail       string  `json:"detail"`
+}
+
+// Score is the failure risk of one drive with the factors that make it up
+type Score struct {
+	Controller int      `json:"controller"`
+	Slot       string   `json:"slot"`
+	Model      string   `json:"model"`
+	Serial     string   `json:"serial,omitempty"`
+	State      string   `json:"state"`
+	Score      float64  `json:"score"`
+	Factors    []Factor `json:"factors"`
+}
+
+// sample is the error count of a drive at one time
+type sample struct {
+	Time   time.Time `json:"time"`
+	Errors int       `json:"errors"`
+}
+
+// memory is what the scorer remembers about one drive across collections
+type memory struct {
+	FirstSeen  time.Time `json:"first_seen"`
+	Samples    []sample  `json:"samples"`
+	Excursions int       `json:"excursions"`
+	Hot        bool      `json:"hot"`
+}
+
+// Scorer combines the error counters, predictive failures, SMART alert,
+// error rate, temperature excursions and time since first seen of every drive into one
+// failure risk score, remembering what it needs across collections
+type Scorer struct {
+	cfg   Config
+	store *state.Store
+
+	mu     sync.RWMutex
+	drives map[string]*memory
+	scores []Score
+}
+
+// New returns a scorer that continues from the memory saved in store.
+// Weights missing from cfg keep their default.
+func New(cfg Config, store *state.Store) (*Scorer, error) {
+	weights := make(map[string]float64)
+	for name, weight := range DefaultWeights {
+		weights[name] = weight
+	}
+	for name, weight := range cfg.Weights {
+		if _, ok := DefaultWeights[name]; !ok {
+			return nil, fmt.Errorf("unknown risk factor: %s", name)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight for risk factor %s", name)
+		}
+		weights[name] = weight
+	}
+	cfg.Weights = weights
+
+	s := &Scorer{cfg: cfg, store: store, drives: make(map[string]*memory)}
+	if _, err := store.Get(stateKey, &s.drives); err != nil {
+		log.Printf("WARNING: Discarding saved risk state: %v", err)
+		s.drives = make(map[string]*memory)
+	}
+	return s, nil
+}
+
+// identity keys the memory of a drive by its WWN or serial number, like the
+// slot tracking in pkg/history, so that a replacement drive in the same slot
+// starts with a clean history. Drives reporting neither are keyed by slot.
+func identity(pd *diskutil.PhysicalDriveStat) string {
+	if drive := pd.Identity(); drive != "" {
+		return "drive:" + drive
+	}
+	return fmt.Sprintf("slot:%d/%s", pd.AdapterIndex, pd.Slot())
+}
+
+// Observe updates the memory of every drive in s and recomputes the scores.
+// Nothing changes when the drive section failed to collect.
+func (s *Scorer) Observe(snapshot *diskutil.Snapshot) {
+	if _, failed := snapshot.Errors[backend.SectionPD]; failed {
+		return
+	}
+	now := snapshot.CollectedAt
+
+	s.mu.Lock()
+	seen := make(map[string]bool)
+	scores := make([]Score, 0, len(snapshot.PhysicalDrives))
+	for _, pd := range snapshot.PhysicalDrives {
+		key := identity(pd)
+		seen[key] = true
+		m, ok := s.drives[key]
+		if !ok {
+			m = &memory{FirstSeen: now}
+			s.drives[key] = m
+		}
+		s.remember(m, pd, now)
+		scores = append(scores, s.score(m, pd, now))
+	}
+	for key := range s.drives {
+		if !seen[key] {
+			delete(s.drives, key)
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	s.scores = scores
+	s.mu.Unlock()
+
+	s.mu.RLock()
+	err := s.store.Put(stateKey, s.drives)
+	s.mu.RUnlock()
+	if err != nil {
+		log.Printf("ERROR: Failed to save risk state: %v", err)
+	}
+}
+
+// remember records the error count and temperature of pd. Samples are only
+// added when the count changes and are dropped once they fall out of the
+// rate window, keeping the newest older one as the baseline.
+func (s *Scorer) remember(m *memory, pd *diskutil.PhysicalDriveStat, now time.Time) {
+	errors := pd.MediaErrorCount + pd.OtherErrorCount
+	if n := len(m.Samples); n == 0 || m.Samples[n-1].Errors != errors {
+		m.Samples = append(m.Samples, sample{Time: now, Errors: errors})
+	}
+	cutoff := now.Add(-s.cfg.RateWindow)
+	for len(m.Samples) > 1 && !m.Samples[1].Time.After(cutoff) {
+		m.Samples = m.Samples[1:]
+	}
+
+	temp := diskutil.ParseTemperature(pd.DriveTemperature)
+	hot := temp > s.cfg.TemperatureLimit
+	if hot && !m.Hot {
+		m.Excursions++
+	}
+	if temp > 0 {
+		m.Hot = hot
+	}
+}
+
+// errorRate returns the errors per day over the rate window, measured from
+// the baseline sample. Drives seen for less than an hour are measured over
+// an hour so that the first errors do not look like a storm.
+func (s *Scorer) errorRate(m *memory, now time.Time) float64 {
+	if len(m.Samples) < 2 {
+		return 0
+	}
+	first, last := m.Samples[0], m.Samples[len(m.Samples)-1]
+	start := first.Time
+	if cutoff := now.Add(-s.cfg.RateWindow); start.Before(cutoff) {
+		start = cutoff
+	}
+	elapsed := now.Sub(start)
+	if elapsed < time.Hour {
+		elapsed = time.Hour
+	}
+	return float64(last.Errors-first.Errors) / elapsed.Hours() * 24
+}
+
+// saturate maps a count to 0..1, reaching 0.5 at half
+func saturate(value, half float64) float64 {
+	if value <= 0 {
+		return 0
+	}
+	return value / (value + half)
+}
+
+func (s *Scorer) score(m *memory, pd *diskutil.PhysicalDriveStat, now time.Time) Score {
+	rate := s.errorRate(m, now)
+	seen := now.Sub(m.FirstSeen)
+	smart := 0.0
+	if strings.EqualFold(pd.SMARTAlertFlagged, "yes") {
+		smart = 1
+	}
+	seenValue := 0.0
+	if s.cfg.ExpectedLifetime > 0 {
+		seenValue = math.Min(1, float64(seen)/float64(s.cfg.ExpectedLifetime))
+	}
+
+	factors := []Factor{
+		{Name: FactorMediaErrors, Value: saturate(float64(pd.MediaErrorCount), halfPoints[FactorMediaErrors]),
+			Detail: fmt.Sprintf("%d media errors", pd.MediaErrorCount)},
+		{Name: FactorOtherErrors, Value: saturate(float64(pd.OtherErrorCount), halfPoints[FactorOtherErrors]),
+			Detail: fmt.Sprintf("%d other errors", pd.OtherErrorCount)},
+		{Name: FactorPredictiveFailures, Value: saturate(float64(pd.PredictiveFailureCount), halfPoints[FactorPredictiveFailures]),
+			Detail: fmt.Sprintf("%d predictive failures", pd.PredictiveFailureCount)},
+		{Name: FactorSMARTAlert, Value: smart,
+			Detail: fmt.Sprintf("SMART alert flagged: %s", valueOr(pd.SMARTAlertFlagged, "not reported"))},
+		{Name: FactorErrorRate, Value: saturate(rate, halfPoints[FactorErrorRate]),
+			Detail: fmt.Sprintf("%.1f errors per day over the last %s", rate, shortDuration(s.cfg.RateWindow))},
+		{Name: FactorTemperature, Value: saturate(float64(m.Excursions), halfPoints[FactorTemperature]),
+			Detail: fmt.Sprintf("%d times above %.0f°C", m.Excursions, s.cfg.TemperatureLimit)},
+		{Name: FactorFirstSeen, Value: seenValue,
+			Detail: fmt.Sprintf("first seen %s ago", shortDuration(seen.Truncate(time.Minute)))},
+	}
+
+	total, weights := 0.0, 0.0
+	for i := range factors {
+		f := &factors[i]
+		f.Weight = s.cfg.Weights[f.Name]
+		weights += f.Weight
+		total += f.Weight * f.Value
+	}
+	for i := range factors {
+		if weights > 0 {
+			factors[i].Contribution = round(100 * factors[i].Weight * factors[i].Value / weights)
+		}
+		factors[i].Value = round(factors[i].Value)
+	}
+	score := 0.0
+	if weights > 0 {
+		score = round(100 * total / weights)
+	}
+	sort.SliceStable(factors, func(i, j int) bool {
+		return factors[i].Contribution > factors[j].Contribution
+	})
+
+	return Score{
+		Controller: pd.AdapterIndex,
+		Slot:       pd.Slot(),
+		Model:      pd.Model,
+		Serial:     pd.SerialNumber,
+		State:      string(pd.NormalizedState()),
+		Score:      score,
+		Factors:    factors,
+	}
+}
+
+// Scores returns the scores from the last collection, highest first
+func (s *Scorer) Scores() []Score {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Score(nil), s.scores...)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// shortDuration formats 24h0m0s as 24h
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
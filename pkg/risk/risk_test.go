@@ -0,0 +1,268 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/state"
+)
+
+var start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// only returns weights that ignore every factor but the given ones
+func only(weights map[string]float64) map[string]float64 {
+	all := make(map[string]float64)
+	for name := range DefaultWeights {
+		all[name] = weights[name]
+	}
+	return all
+}
+
+func newScorer(t *testing.T, cfg Config) *Scorer {
+	t.Helper()
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func factor(t *testing.T, score Score, name string) Factor {
+	t.Helper()
+	for _, f := range score.Factors {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("no factor %s in %+v", name, score.Factors)
+	return Factor{}
+}
+
+// observe scores one drive at start+offset and returns its score
+func observe(s *Scorer, offset time.Duration, pd diskutil.PhysicalDriveStat) Score {
+	if pd.EnclosureDeviceId == 0 {
+		pd.EnclosureDeviceId = 32
+	}
+	if pd.FirmwareState == "" {
+		pd.FirmwareState = "Onln"
+	}
+	s.Observe(&diskutil.Snapshot{CollectedAt: start.Add(offset), PhysicalDrives: []*diskutil.PhysicalDriveStat{&pd}})
+	return s.Scores()[0]
+}
+
+func TestNewWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		err     bool
+	}{
+		{"defaults", nil, false},
+		{"override", map[string]float64{FactorMediaErrors: 50}, false},
+		{"zero", map[string]float64{FactorFirstSeen: 0}, false},
+		{"unknown factor", map[string]float64{"power_on_hours": 5}, true},
+		{"negative", map[string]float64{FactorMediaErrors: -1}, true},
+	}
+	for _, tt := range tests {
+		store, err := state.Open("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(Config{Weights: tt.weights}, store); (err != nil) != tt.err {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestWeighting(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		drive   diskutil.PhysicalDriveStat
+		score   float64
+	}{
+		{
+			name:  "healthy drive with default weights",
+			drive: diskutil.PhysicalDriveStat{SerialNumber: "A", SMARTAlertFlagged: "No"},
+			score: 0,
+		},
+		{
+			name:    "media errors at the half point",
+			weights: only(map[string]float64{FactorMediaErrors: 1}),
+			drive:   diskutil.PhysicalDriveStat{SerialNumber: "A", MediaErrorCount: 10},
+			score:   50,
+		},
+		{
+			name:    "weighted average",
+			weights: only(map[string]float64{FactorMediaErrors: 3, FactorSMARTAlert: 1}),
+			drive:   diskutil.PhysicalDriveStat{SerialNumber: "A", MediaErrorCount: 10, SMARTAlertFlagged: "Yes"},
+			score:   62.5,
+		},
+		{
+			name:    "ignored factor",
+			weights: only(map[string]float64{FactorMediaErrors: 1}),
+			drive:   diskutil.PhysicalDriveStat{SerialNumber: "A", PredictiveFailureCount: 5, SMARTAlertFlagged: "Yes"},
+			score:   0,
+		},
+		{
+			name:    "one predictive failure",
+			weights: only(map[string]float64{FactorPredictiveFailures: 1, FactorOtherErrors: 1}),
+			drive:   diskutil.PhysicalDriveStat{SerialNumber: "A", PredictiveFailureCount: 1},
+			score:   25,
+		},
+		{
+			name:    "counters saturate below 100",
+			weights: only(map[string]float64{FactorMediaErrors: 1}),
+			drive:   diskutil.PhysicalDriveStat{SerialNumber: "A", MediaErrorCount: 990},
+			score:   99,
+		},
+		{
+			name:    "no weights",
+			weights: only(nil),
+			drive:   diskutil.PhysicalDriveStat{SerialNumber: "A", MediaErrorCount: 10, SMARTAlertFlagged: "Yes"},
+			score:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScorer(t, Config{Weights: tt.weights, RateWindow: 24 * time.Hour, TemperatureLimit: 50})
+			score := observe(s, 0, tt.drive)
+			if score.Score != tt.score {
+				t.Errorf("got score %v, want %v: %+v", score.Score, tt.score, score.Factors)
+			}
+			total := 0.0
+			for _, f := range score.Factors {
+				total += f.Contribution
+			}
+			if total < score.Score-0.1 || total > score.Score+0.1 {
+				t.Errorf("contributions add up to %v, score is %v", total, score.Score)
+			}
+		})
+	}
+}
+
+func TestErrorRate(t *testing.T) {
+	type poll struct {
+		offset time.Duration
+		errors int
+	}
+	tests := []struct {
+		name  string
+		polls []poll
+		rate  float64
+	}{
+		{"first collection", []poll{{0, 10}}, 0},
+		{"no new errors", []poll{{0, 10}, {12 * time.Hour, 10}}, 0},
+		{"ten errors in a day", []poll{{0, 0}, {24 * time.Hour, 10}}, 10},
+		{"new drive is measured over an hour", []poll{{0, 0}, {10 * time.Minute, 5}}, 120},
+		{"only the window counts", []poll{{0, 0}, {time.Hour, 10}, {48 * time.Hour, 20}}, 10},
+		{"errors older than the window", []poll{{0, 0}, {time.Hour, 10}, {49 * time.Hour, 10}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScorer(t, Config{RateWindow: 24 * time.Hour, TemperatureLimit: 50})
+			var score Score
+			for _, p := range tt.polls {
+				score = observe(s, p.offset, diskutil.PhysicalDriveStat{SerialNumber: "A", MediaErrorCount: p.errors})
+			}
+			f := factor(t, score, FactorErrorRate)
+			if want := round(saturate(tt.rate, halfPoints[FactorErrorRate])); f.Value != want {
+				t.Errorf("got %v (%s), want %v errors per day", f.Value, f.Detail, tt.rate)
+			}
+		})
+	}
+}
+
+func TestTemperatureExcursions(t *testing.T) {
+	tests := []struct {
+		name       string
+		readings   []string
+		excursions int
+	}{
+		{"never hot", []string{"35C", "45C", "50C"}, 0},
+		{"one long excursion", []string{"45C", "55C", "56C", "58C"}, 1},
+		{"cooling down starts a new one", []string{"55C", "45C", "55C", "45C", "55C"}, 3},
+		{"missing readings do not end an excursion", []string{"55C", "N/A", "", "55C"}, 1},
+		{"many excursions", []string{"55C", "45C", "55C", "45C", "55C", "45C", "55C", "45C", "55C", "45C", "55C", "45C", "55C"}, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScorer(t, Config{Weights: only(map[string]float64{FactorTemperature: 1}), RateWindow: 24 * time.Hour, TemperatureLimit: 50})
+			var score Score
+			for i, reading := range tt.readings {
+				score = observe(s, time.Duration(i)*time.Minute, diskutil.PhysicalDriveStat{SerialNumber: "A", DriveTemperature: reading})
+			}
+			want := round(saturate(float64(tt.excursions), halfPoints[FactorTemperature]))
+			if f := factor(t, score, FactorTemperature); f.Value != want {
+				t.Errorf("got %v (%s), want %d excursions", f.Value, f.Detail, tt.excursions)
+			}
+			if score.Score >= 100 {
+				t.Errorf("excursions did not saturate: score %v", score.Score)
+			}
+		})
+	}
+}
+
+func TestReplacementStartsCleanHistory(t *testing.T) {
+	tests := []struct {
+		name        string
+		first, next diskutil.PhysicalDriveStat
+		clean       bool
+	}{
+		{
+			name:  "same serial",
+			first: diskutil.PhysicalDriveStat{SerialNumber: "A"},
+			next:  diskutil.PhysicalDriveStat{SerialNumber: "A"},
+		},
+		{
+			name:  "different serial",
+			first: diskutil.PhysicalDriveStat{SerialNumber: "A"},
+			next:  diskutil.PhysicalDriveStat{SerialNumber: "B"},
+			clean: true,
+		},
+		{
+			name:  "different WWN without serial",
+			first: diskutil.PhysicalDriveStat{WWN: "5000C500AAAA0000"},
+			next:  diskutil.PhysicalDriveStat{WWN: "5000C500BBBB0000"},
+			clean: true,
+		},
+		{
+			name:  "WWN decides over serial",
+			first: diskutil.PhysicalDriveStat{WWN: "5000C500AAAA0000", SerialNumber: "A"},
+			next:  diskutil.PhysicalDriveStat{WWN: "5000C500AAAA0000", SerialNumber: "A "},
+		},
+		{
+			name:  "no identity",
+			first: diskutil.PhysicalDriveStat{},
+			next:  diskutil.PhysicalDriveStat{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScorer(t, Config{
+				Weights:          only(map[string]float64{FactorFirstSeen: 1}),
+				RateWindow:       24 * time.Hour,
+				TemperatureLimit: 50,
+				ExpectedLifetime: 100 * time.Hour,
+			})
+			observe(s, 0, tt.first)
+			score := observe(s, 50*time.Hour, tt.next)
+
+			want := 50.0
+			if tt.clean {
+				want = 0
+			}
+			if score.Score != want {
+				t.Errorf("got score %v, want %v: %s", score.Score, want, factor(t, score, FactorFirstSeen).Detail)
+			}
+		})
+	}
+}
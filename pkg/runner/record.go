@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Record is one command run through a Recorder
+type Record struct {
+	Args   []string
+	Output []byte
+	Err    error
+}
+
+// Recorder runs commands through another runner and keeps their output, so
+// support bundles contain exactly the commands a backend uses
+type Recorder struct {
+	Runner Runner
+
+	mu      sync.Mutex
+	records []Record
+}
+
+func (r *Recorder) Run(path string, args ...string) ([]byte, error) {
+	output, err := r.Runner.Run(path, args...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, Record{
+		Args:   append([]string(nil), args...),
+		Output: output,
+		Err:    err,
+	})
+	return output, err
+}
+
+// Records returns the commands run so far, in order
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Replay answers commands from recorded output instead of running them, to
+// parse a support bundle with the same backends that produced it
+type Replay struct {
+	records map[string]Record
+}
+
+func NewReplay(records []Record) *Replay {
+	replay := &Replay{records: make(map[string]Record)}
+	for _, record := range records {
+		replay.records[strings.Join(record.Args, " ")] = record
+	}
+	return replay
+}
+
+func (r *Replay) Run(path string, args ...string) ([]byte, error) {
+	record, ok := r.records[strings.Join(args, " ")]
+	if !ok {
+		return nil, fmt.Errorf("command not recorded: %s %s", path, strings.Join(args, " "))
+	}
+	return record.Output, record.Err
+}
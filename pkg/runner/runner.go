@@ -0,0 +1,27 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// Runner executes a RAID CLI command and returns its standard output
+type Runner interface {
+	Run(path string, args ...string) ([]byte, error)
+}
+
+// Local runs commands on this host, killing them after Timeout when it is set
+type Local struct {
+	Timeout time.Duration
+}
+
+func (l Local) Run(path string, args ...string) ([]byte, error) {
+	if l.Timeout <= 0 {
+		return exec.Command(path, args...).Output()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout)
+	defer cancel()
+	return exec.CommandContext(ctx, path, args...).Output()
+}
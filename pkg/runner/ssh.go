@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig describes how remote targets are reached
+type SSHConfig struct {
+	User    string        `yaml:"user"`
+	Port    int           `yaml:"port"`
+	KeyFile string        `yaml:"key_file"`
+	Timeout time.Duration `yaml:"timeout"`
+	// KnownHostsFile is used to verify host keys. Verification is only
+	// skipped when InsecureIgnoreHostKey is set explicitly.
+	KnownHostsFile        string `yaml:"known_hosts_file"`
+	InsecureIgnoreHostKey bool   `yaml:"insecure_ignore_host_key"`
+	// Sudo prefixes every command with "sudo -n" for non-root logins
+	Sudo bool `yaml:"sudo"`
+}
+
+// SSH runs commands on a remote host over a single SSH connection
+type SSH struct {
+	client  *ssh.Client
+	sudo    bool
+	timeout time.Duration
+}
+
+// DialSSH connects to target, which is a host name optionally followed by ":port"
+func DialSSH(cfg SSHConfig, target string) (*SSH, error) {
+	key, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %v", cfg.KeyFile, err)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !cfg.InsecureIgnoreHostKey {
+		if cfg.KnownHostsFile == "" {
+			return nil, fmt.Errorf("known_hosts_file is required unless insecure_ignore_host_key is set")
+		}
+		if hostKeyCallback, err = knownhosts.New(cfg.KnownHostsFile); err != nil {
+			return nil, fmt.Errorf("failed to load known hosts: %v", err)
+		}
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	addr := target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		addr = net.JoinHostPort(target, strconv.Itoa(port))
+	}
+
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         cfg.Timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+
+	return &SSH{client: client, sudo: cfg.Sudo, timeout: cfg.Timeout}, nil
+}
+
+func (s *SSH) Run(path string, args ...string) ([]byte, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session: %v", err)
+	}
+	defer session.Close()
+
+	words := append([]string{path}, args...)
+	if s.sudo {
+		words = append([]string{"sudo", "-n"}, words...)
+	}
+	command := shellJoin(words)
+
+	if s.timeout > 0 {
+		timer := time.AfterFunc(s.timeout, func() { session.Close() })
+		defer timer.Stop()
+	}
+
+	return session.Output(command)
+}
+
+func (s *SSH) Close() error {
+	return s.client.Close()
+}
+
+// shellJoin quotes words for the remote POSIX shell
+func shellJoin(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,150 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the state file inside the state directory
+const FileName = "state.json"
+
+// fileVersion is bumped when the layout of the state file changes
+const fileVersion = 1
+
+type file struct {
+	Version int                        `json:"version"`
+	Entries map[string]json.RawMessage `json:"entries"`
+}
+
+// Store keeps what the exporter's subsystems need to remember across
+// restarts (counters, transition history, kernel log positions, when drives
+// were first seen) in one small JSON file. Each subsystem owns one key.
+//
+// Every Put rewrites the file through a temporary file, fsync and rename, so
+// a crash leaves either the old or the new state on disk, never a mix. A
+// store opened with an empty directory only keeps state in memory.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]json.RawMessage
+}
+
+// Open loads the state from dir, creating the directory if needed. A state
+// file that cannot be parsed is moved aside and the store starts empty.
+func Open(dir string) (*Store, error) {
+	s := &Store{entries: make(map[string]json.RawMessage)}
+	if dir == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %v", err)
+	}
+	s.path = filepath.Join(dir, FileName)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %v", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != fileVersion {
+		corrupt := fmt.Sprintf("%s.corrupt-%d", s.path, time.Now().Unix())
+		log.Printf("WARNING: Ignoring unreadable state file %s, moved to %s", s.path, corrupt)
+		if err := os.Rename(s.path, corrupt); err != nil {
+			return nil, fmt.Errorf("failed to move unreadable state aside: %v", err)
+		}
+		return s, nil
+	}
+	if f.Entries != nil {
+		s.entries = f.Entries
+	}
+	return s, nil
+}
+
+// Path returns the state file, or "" for a store that only keeps state in memory
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get decodes the value stored under key into v and reports whether there was one
+func (s *Store) Get(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	raw, ok := s.entries[key]
+	s.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("failed to decode state %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Put stores v under key and writes the state file when the value changed
+func (s *Store) Put(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %s: %v", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bytes.Equal(s.entries[key], raw) {
+		return nil
+	}
+	s.entries[key] = raw
+	if s.path == "" {
+		return nil
+	}
+	return s.write()
+}
+
+// write replaces the state file atomically. s.mu must be held.
+func (s *Store) write() error {
+	data, err := json.Marshal(file{Version: fileVersion, Entries: s.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %v", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, FileName+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state: %v", err)
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
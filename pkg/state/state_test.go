@@ -0,0 +1,216 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type counters struct {
+	Resets int `json:"resets"`
+}
+
+// files returns the names in dir
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestPutAndReopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Path() != filepath.Join(dir, FileName) {
+		t.Errorf("got path %s", store.Path())
+	}
+	if err := store.Put("kmsg", counters{Resets: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the state file is left, no temporary files
+	if names := files(t, dir); len(names) != 1 || names[0] != FileName {
+		t.Errorf("got files %v, want only %s", names, FileName)
+	}
+	var f file
+	data, err := os.ReadFile(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != fileVersion {
+		t.Errorf("unexpected state file %s: %v", data, err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c counters
+	if ok, err := reopened.Get("kmsg", &c); !ok || err != nil || c.Resets != 3 {
+		t.Errorf("got %+v, %v, %v after reopening, want 3 resets", c, ok, err)
+	}
+	if ok, err := reopened.Get("history", &c); ok || err != nil {
+		t.Errorf("got %v, %v for a missing key", ok, err)
+	}
+}
+
+func TestPutUnchangedDoesNotWrite(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("kmsg", counters{Resets: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(store.Path()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("kmsg", counters{Resets: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.Path()); !os.IsNotExist(err) {
+		t.Errorf("an unchanged value rewrote the state file: %v", err)
+	}
+}
+
+func TestCrashDuringWrite(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("kmsg", counters{Resets: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash before the rename leaves a partial temporary file behind
+	partial := filepath.Join(dir, FileName+".tmp-123")
+	if err := os.WriteFile(partial, []byte(`{"version":1,"entries":{"kmsg":{"res`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c counters
+	if ok, err := reopened.Get("kmsg", &c); !ok || err != nil || c.Resets != 1 {
+		t.Errorf("got %+v, %v, %v, want the state before the crash", c, ok, err)
+	}
+}
+
+func TestFailedWriteRemovesTemporaryFile(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rename fails when a non-empty directory is in the way
+	if err := os.MkdirAll(filepath.Join(store.Path(), "blocked"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("kmsg", counters{Resets: 1}); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, name := range files(t, dir) {
+		if strings.Contains(name, ".tmp-") {
+			t.Errorf("temporary file %s left behind", name)
+		}
+	}
+}
+
+func TestOpenUnreadable(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"corrupt", `{"version":1,"entries":{"kmsg":`},
+		{"not json", "resets=3\n"},
+		{"newer version", `{"version":2,"entries":{"kmsg":{"resets":3}}}`},
+		{"no version", `{"entries":{"kmsg":{"resets":3}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var c counters
+			if ok, _ := store.Get("kmsg", &c); ok {
+				t.Errorf("unreadable state was loaded: %+v", c)
+			}
+
+			names := files(t, dir)
+			if len(names) != 1 || !strings.HasPrefix(names[0], FileName+".corrupt-") {
+				t.Fatalf("got files %v, want the state moved aside", names)
+			}
+			kept, err := os.ReadFile(filepath.Join(dir, names[0]))
+			if err != nil || string(kept) != tt.content {
+				t.Errorf("moved state differs: %q, %v", kept, err)
+			}
+		})
+	}
+}
+
+func TestInMemory(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	store, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Path() != "" {
+		t.Errorf("got path %q, want none", store.Path())
+	}
+	if err := store.Put("kmsg", counters{Resets: 2}); err != nil {
+		t.Fatal(err)
+	}
+	var c counters
+	if ok, err := store.Get("kmsg", &c); !ok || err != nil || c.Resets != 2 {
+		t.Errorf("got %+v, %v, %v, want 2 resets", c, ok, err)
+	}
+	if names := files(t, dir); len(names) != 0 {
+		t.Errorf("in-memory store wrote %v", names)
+	}
+}
+
+func TestGetDecodeError(t *testing.T) {
+	store, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("kmsg", "three"); err != nil {
+		t.Fatal(err)
+	}
+	var c counters
+	if ok, err := store.Get("kmsg", &c); ok || err == nil {
+		t.Errorf("got %v, %v, want a decode error", ok, err)
+	}
+}
@@ -0,0 +1,240 @@
+package threshold
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+// Names of the values thresholds apply to, also used as the keys of
+// Config.Limits and Rule.Limits
+const (
+	ControllerTemperature = "controller_temperature"
+	ROCTemperature        = "roc_temperature"
+	DriveTemperature      = "drive_temperature"
+	BBUTemperature        = "bbu_temperature"
+	MediaErrors           = "media_errors"
+	OtherErrors           = "other_errors"
+	PredictiveFailures    = "predictive_failures"
+)
+
+var names = []string{
+	ControllerTemperature, ROCTemperature, DriveTemperature, BBUTemperature,
+	MediaErrors, OtherErrors, PredictiveFailures,
+}
+
+// Limit is the warning and critical level of a value. A value at or above a
+// level exceeds it; 0 disables the level.
+type Limit struct {
+	Warning  float64 `yaml:"warning" json:"warning"`
+	Critical float64 `yaml:"critical" json:"critical"`
+}
+
+// Rule overrides limits for the components it matches. Both patterns are
+// regular expressions matched against the whole model or component name
+// ("controller 0", "pd 0/32:1", "bbu 0", "cachevault 0"); an empty pattern
+// matches everything.
+type Rule struct {
+	Model     string           `yaml:"model"`
+	Component string           `yaml:"component"`
+	Limits    map[string]Limit `yaml:"limits"`
+}
+
+// Config mirrors the thresholds section of config.yaml
+type Config struct {
+	// Limits apply to every component unless a rule overrides them
+	Limits map[string]Limit `yaml:"limits"`
+	// Rules are applied in order, a later match wins
+	Rules []Rule `yaml:"rules"`
+}
+
+// Result is a value that exceeds its warning or critical level
+type Result struct {
+	Severity  diskutil.Severity `json:"severity"`
+	Component string            `json:"component"`
+	Name      string            `json:"name"`
+	Value     float64           `json:"value"`
+	// Level is the warning or critical level that was exceeded
+	Level float64 `json:"level"`
+}
+
+// Problem describes the result like the problems found in a snapshot
+func (r Result) Problem() diskutil.Problem {
+	return diskutil.Problem{
+		Severity:  r.Severity,
+		Component: r.Component,
+		Message: fmt.Sprintf("%s is %s, %s level is %s",
+			r.Name, format(r.Value), r.Severity, format(r.Level)),
+	}
+}
+
+type rule struct {
+	model     *regexp.Regexp
+	component *regexp.Regexp
+	limits    map[string]Limit
+}
+
+func (r rule) matches(component, model string) bool {
+	return (r.model == nil || r.model.MatchString(model)) &&
+		(r.component == nil || r.component.MatchString(component))
+}
+
+// Evaluator checks the values in a snapshot against the configured limits.
+// A nil Evaluator has no limits.
+type Evaluator struct {
+	limits map[string]Limit
+	rules  []rule
+}
+
+// New validates the configuration and compiles the rules
+func New(cfg Config) (*Evaluator, error) {
+	if err := validate("limits", cfg.Limits); err != nil {
+		return nil, err
+	}
+	e := &Evaluator{limits: cfg.Limits}
+	for i, r := range cfg.Rules {
+		where := fmt.Sprintf("rule %d", i+1)
+		if err := validate(where, r.Limits); err != nil {
+			return nil, err
+		}
+		compiled := rule{limits: r.Limits}
+		var err error
+		if compiled.model, err = compile(r.Model); err != nil {
+			return nil, fmt.Errorf("%s: invalid model pattern: %v", where, err)
+		}
+		if compiled.component, err = compile(r.Component); err != nil {
+			return nil, fmt.Errorf("%s: invalid component pattern: %v", where, err)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+func validate(where string, limits map[string]Limit) error {
+	for name, limit := range limits {
+		known := false
+		for _, n := range names {
+			known = known || n == name
+		}
+		if !known {
+			return fmt.Errorf("%s: unknown value %q", where, name)
+		}
+		if limit.Warning > 0 && limit.Critical > 0 && limit.Warning > limit.Critical {
+			return fmt.Errorf("%s: %s warning level %s is above critical level %s",
+				where, name, format(limit.Warning), format(limit.Critical))
+		}
+	}
+	return nil
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// Limit returns the limit of a value for a component, after applying the
+// rules that match it
+func (e *Evaluator) Limit(name, component, model string) Limit {
+	if e == nil {
+		return Limit{}
+	}
+	limit := e.limits[name]
+	for _, r := range e.rules {
+		if l, ok := r.limits[name]; ok && r.matches(component, model) {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// Check returns the severity of a value for a component
+func (e *Evaluator) Check(name, component, model string, value float64) diskutil.Severity {
+	severity, _ := e.check(name, component, model, value)
+	return severity
+}
+
+func (e *Evaluator) check(name, component, model string, value float64) (diskutil.Severity, float64) {
+	limit := e.Limit(name, component, model)
+	switch {
+	case limit.Critical > 0 && value >= limit.Critical:
+		return diskutil.SeverityCritical, limit.Critical
+	case limit.Warning > 0 && value >= limit.Warning:
+		return diskutil.SeverityWarning, limit.Warning
+	}
+	return diskutil.SeverityOK, 0
+}
+
+// Evaluate returns every value in the snapshot that exceeds a level. A
+// temperature of 0 means it was not reported and is not checked.
+func (e *Evaluator) Evaluate(s *diskutil.Snapshot) []Result {
+	if e == nil {
+		return nil
+	}
+
+	var results []Result
+	add := func(name, component, model string, value float64, temperature bool) {
+		if temperature && value <= 0 {
+			return
+		}
+		if severity, level := e.check(name, component, model, value); severity != diskutil.SeverityOK {
+			results = append(results, Result{
+				Severity:  severity,
+				Component: component,
+				Name:      name,
+				Value:     value,
+				Level:     level,
+			})
+		}
+	}
+
+	for _, ctrl := range s.Controllers {
+		component := fmt.Sprintf("controller %d", ctrl.AdapterIndex)
+		add(ControllerTemperature, component, ctrl.ProductName, float64(ctrl.ControllerTemperature), true)
+		add(ROCTemperature, component, ctrl.ProductName, float64(ctrl.ROCTemperature), true)
+	}
+	for _, pd := range s.PhysicalDrives {
+		component := fmt.Sprintf("pd %d/%s", pd.AdapterIndex, pd.Slot())
+		add(DriveTemperature, component, pd.Model, diskutil.ParseTemperature(pd.DriveTemperature), true)
+		add(MediaErrors, component, pd.Model, float64(pd.MediaErrorCount), false)
+		add(OtherErrors, component, pd.Model, float64(pd.OtherErrorCount), false)
+		add(PredictiveFailures, component, pd.Model, float64(pd.PredictiveFailureCount), false)
+	}
+	for _, bbu := range s.Batteries {
+		component := fmt.Sprintf("%s %d", bbu.Kind(), bbu.AdapterIndex)
+		add(BBUTemperature, component, bbu.BatteryType, float64(bbu.Temperature), true)
+	}
+	return results
+}
+
+// Problems returns the problems found in the snapshot together with the
+// exceeded thresholds, most severe first
+func (e *Evaluator) Problems(s *diskutil.Snapshot) []diskutil.Problem {
+	problems := s.Problems()
+	for _, r := range e.Evaluate(s) {
+		problems = append(problems, r.Problem())
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Severity > problems[j].Severity
+	})
+	return problems
+}
+
+// Severity returns the most severe problem or exceeded threshold in the snapshot
+func (e *Evaluator) Severity(s *diskutil.Snapshot) diskutil.Severity {
+	severity := s.Severity()
+	for _, r := range e.Evaluate(s) {
+		if r.Severity > severity {
+			severity = r.Severity
+		}
+	}
+	return severity
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,153 @@
+package threshold
+
+import (
+	"testing"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  bool
+	}{
+		{"empty", Config{}, false},
+		{"limits", Config{Limits: map[string]Limit{DriveTemperature: {Warning: 50, Critical: 60}}}, false},
+		{"warning only", Config{Limits: map[string]Limit{MediaErrors: {Warning: 1}}}, false},
+		{"unknown value", Config{Limits: map[string]Limit{"fan_speed": {Warning: 1}}}, true},
+		{"warning above critical", Config{Limits: map[string]Limit{DriveTemperature: {Warning: 60, Critical: 50}}}, true},
+		{"rule with unknown value", Config{Rules: []Rule{{Limits: map[string]Limit{"fan_speed": {Warning: 1}}}}}, true},
+		{"invalid model pattern", Config{Rules: []Rule{{Model: "ST(", Limits: map[string]Limit{}}}}, true},
+		{"invalid component pattern", Config{Rules: []Rule{{Component: "pd [", Limits: map[string]Limit{}}}}, true},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); (err != nil) != tt.err {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	e, err := New(Config{
+		Limits: map[string]Limit{
+			DriveTemperature: {Warning: 50, Critical: 60},
+			MediaErrors:      {Critical: 10},
+		},
+		Rules: []Rule{
+			// SSDs run hotter
+			{Model: "MZ.*", Limits: map[string]Limit{DriveTemperature: {Warning: 60, Critical: 70}}},
+			{Component: "pd 0/32:7", Limits: map[string]Limit{DriveTemperature: {Warning: 65}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		value     string
+		component string
+		model     string
+		v         float64
+		want      diskutil.Severity
+	}{
+		{"below warning", DriveTemperature, "pd 0/32:0", "ST1000", 49, diskutil.SeverityOK},
+		{"at warning", DriveTemperature, "pd 0/32:0", "ST1000", 50, diskutil.SeverityWarning},
+		{"at critical", DriveTemperature, "pd 0/32:0", "ST1000", 60, diskutil.SeverityCritical},
+		{"model rule", DriveTemperature, "pd 0/32:0", "MZ7LH960", 60, diskutil.SeverityWarning},
+		{"model rule is anchored", DriveTemperature, "pd 0/32:0", "XMZ7LH960", 60, diskutil.SeverityCritical},
+		{"later rule wins", DriveTemperature, "pd 0/32:7", "MZ7LH960", 69, diskutil.SeverityWarning},
+		{"later rule disables critical", DriveTemperature, "pd 0/32:7", "MZ7LH960", 90, diskutil.SeverityWarning},
+		{"critical only", MediaErrors, "pd 0/32:0", "ST1000", 9, diskutil.SeverityOK},
+		{"critical only reached", MediaErrors, "pd 0/32:0", "ST1000", 10, diskutil.SeverityCritical},
+		{"no limit", OtherErrors, "pd 0/32:0", "ST1000", 1000, diskutil.SeverityOK},
+	}
+	for _, tt := range tests {
+		if got := e.Check(tt.value, tt.component, tt.model, tt.v); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	var none *Evaluator
+	if got := none.Check(DriveTemperature, "pd 0/32:0", "ST1000", 100); got != diskutil.SeverityOK {
+		t.Errorf("nil evaluator: got %s, want ok", got)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	e, err := New(Config{Limits: map[string]Limit{
+		ControllerTemperature: {Warning: 80},
+		ROCTemperature:        {Warning: 90, Critical: 100},
+		DriveTemperature:      {Warning: 50, Critical: 60},
+		BBUTemperature:        {Warning: 45},
+		PredictiveFailures:    {Warning: 1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		snapshot *diskutil.Snapshot
+		want     []Result
+		severity diskutil.Severity
+	}{
+		{
+			name:     "empty",
+			snapshot: &diskutil.Snapshot{},
+			severity: diskutil.SeverityOK,
+		},
+		{
+			name: "unreported temperatures are not checked",
+			snapshot: &diskutil.Snapshot{
+				Controllers:    []*diskutil.ControllerStat{{ControllerStatus: "Optimal"}},
+				PhysicalDrives: []*diskutil.PhysicalDriveStat{{EnclosureDeviceId: 32, FirmwareState: "Onln", DriveTemperature: "N/A"}},
+			},
+			severity: diskutil.SeverityOK,
+		},
+		{
+			name: "hot controller and drive",
+			snapshot: &diskutil.Snapshot{
+				Controllers: []*diskutil.ControllerStat{{ControllerStatus: "Optimal", ROCTemperature: 101}},
+				PhysicalDrives: []*diskutil.PhysicalDriveStat{
+					{EnclosureDeviceId: 32, SlotNumber: 1, FirmwareState: "Onln", DriveTemperature: "52C"},
+				},
+			},
+			want: []Result{
+				{Severity: diskutil.SeverityCritical, Component: "controller 0", Name: ROCTemperature, Value: 101, Level: 100},
+				{Severity: diskutil.SeverityWarning, Component: "pd 0/32:1", Name: DriveTemperature, Value: 52, Level: 50},
+			},
+			severity: diskutil.SeverityCritical,
+		},
+		{
+			name: "predictive failure and warm cachevault",
+			snapshot: &diskutil.Snapshot{
+				PhysicalDrives: []*diskutil.PhysicalDriveStat{{EnclosureDeviceId: 32, FirmwareState: "Onln", PredictiveFailureCount: 3}},
+				Batteries:      []*diskutil.BatteryBackupStat{{BatteryType: "CVPM02", BatteryState: "Optimal", Temperature: 45}},
+			},
+			want: []Result{
+				{Severity: diskutil.SeverityWarning, Component: "pd 0/32:0", Name: PredictiveFailures, Value: 3, Level: 1},
+				{Severity: diskutil.SeverityWarning, Component: "cachevault 0", Name: BBUTemperature, Value: 45, Level: 45},
+			},
+			severity: diskutil.SeverityWarning,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.Evaluate(tt.snapshot)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("result %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+			if severity := e.Severity(tt.snapshot); severity != tt.severity {
+				t.Errorf("got severity %s, want %s", severity, tt.severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+// EnclosurePrefix is the path enclosure images are served under
+const EnclosurePrefix = "/ui/enclosure/"
+
+// Slot grid geometry in pixels
+const (
+	slotWidth     = 64
+	slotHeight    = 96
+	slotGap       = 6
+	slotsPerRow   = 12
+	headerHeight  = 28
+	enclosurePad  = 10
+	tempBarHeight = 8
+	minWidth      = 240 // room for the header
+)
+
+// Fill colours by severity, and for bays with no drive
+var slotColours = map[string]string{
+	diskutil.SeverityOK.String():       "#2e7d32",
+	diskutil.SeverityWarning.String():  "#ef8f00",
+	diskutil.SeverityCritical.String(): "#c62828",
+	"":                                 "#bdbdbd",
+}
+
+// enclosure identifies one enclosure on one controller
+type enclosure struct {
+	Controller int
+	ID         int
+}
+
+func (e enclosure) Path() string {
+	return fmt.Sprintf("%s%d/%d.svg", EnclosurePrefix, e.Controller, e.ID)
+}
+
+// enclosures lists the enclosures that have drives in the snapshot
+func enclosures(s *diskutil.Snapshot) []enclosure {
+	seen := make(map[enclosure]bool)
+	var result []enclosure
+	for _, pd := range s.PhysicalDrives {
+		e := enclosure{Controller: pd.AdapterIndex, ID: pd.EnclosureDeviceId}
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Controller != result[j].Controller {
+			return result[i].Controller < result[j].Controller
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Enclosure draws /ui/enclosure/<ctl>/<eid>.svg: one box per bay from slot 0
+// up to the highest occupied slot, filled by drive state, with a bar along
+// the bottom coloured by temperature. Hovering a bay shows the drive details.
+func (h *Handler) Enclosure(w http.ResponseWriter, r *http.Request) {
+	e, err := parseEnclosurePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	snapshot := h.poller.Snapshot()
+	if snapshot == nil {
+		http.Error(w, "no data collected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	drives := make(map[int]*diskutil.PhysicalDriveStat)
+	slots := 0
+	for _, pd := range snapshot.PhysicalDrives {
+		if pd.AdapterIndex != e.Controller || pd.EnclosureDeviceId != e.ID {
+			continue
+		}
+		drives[pd.SlotNumber] = pd
+		if pd.SlotNumber+1 > slots {
+			slots = pd.SlotNumber + 1
+		}
+	}
+	if len(drives) == 0 {
+		http.Error(w, fmt.Sprintf("no drives in enclosure %d on controller %d", e.ID, e.Controller), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write([]byte(drawEnclosure(e, slots, drives, h.thresholds)))
+}
+
+func parseEnclosurePath(path string) (enclosure, error) {
+	parts := strings.Split(strings.TrimPrefix(path, EnclosurePrefix), "/")
+	if len(parts) != 2 || !strings.HasSuffix(parts[1], ".svg") {
+		return enclosure{}, fmt.Errorf("expected %s<controller>/<enclosure>.svg", EnclosurePrefix)
+	}
+	ctl, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return enclosure{}, fmt.Errorf("invalid controller: %s", parts[0])
+	}
+	eid, err := strconv.Atoi(strings.TrimSuffix(parts[1], ".svg"))
+	if err != nil {
+		return enclosure{}, fmt.Errorf("invalid enclosure: %s", parts[1])
+	}
+	return enclosure{Controller: ctl, ID: eid}, nil
+}
+
+func drawEnclosure(e enclosure, slots int, drives map[int]*diskutil.PhysicalDriveStat, t *threshold.Evaluator) string {
+	columns := slots
+	if columns > slotsPerRow {
+		columns = slotsPerRow
+	}
+	rows := (slots + slotsPerRow - 1) / slotsPerRow
+	width := 2*enclosurePad + columns*slotWidth + (columns-1)*slotGap
+	if width < minWidth {
+		width = minWidth
+	}
+	height := 2*enclosurePad + headerHeight + rows*slotHeight + (rows-1)*slotGap
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" rx="6" fill="#424242"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" fill="#fff">Controller %d, enclosure %d</text>`+"\n",
+		enclosurePad, enclosurePad+16, e.Controller, e.ID)
+
+	for slot := 0; slot < slots; slot++ {
+		x := enclosurePad + (slot%slotsPerRow)*(slotWidth+slotGap)
+		y := enclosurePad + headerHeight + (slot/slotsPerRow)*(slotHeight+slotGap)
+		pd := drives[slot]
+
+		b.WriteString("<g>\n")
+		fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(slotTitle(e, slot, pd, t)))
+		severity := ""
+		if pd != nil {
+			severity = pd.NormalizedState().Severity().String()
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="4" fill="%s" stroke="#212121"/>`+"\n",
+			x, y, slotWidth, slotHeight, slotColours[severity])
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="13" fill="#fff" text-anchor="middle">%d</text>`+"\n",
+			x+slotWidth/2, y+20, slot)
+		if pd != nil {
+			state := string(pd.NormalizedState())
+			fit := ""
+			if len(state) > 10 {
+				// Squeeze long states such as unconfigured_good into the bay
+				fit = fmt.Sprintf(` textLength="%d" lengthAdjust="spacingAndGlyphs"`, slotWidth-8)
+			}
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="#fff" text-anchor="middle"%s>%s</text>`+"\n",
+				x+slotWidth/2, y+slotHeight/2+4, fit, html.EscapeString(state))
+			if temp := driveTemperature(t, pd); temp.Class != "" {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#212121"/>`+"\n",
+					x+4, y+slotHeight-tempBarHeight-4, slotWidth-8, tempBarHeight, slotColours[temp.Class])
+				fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="#fff" text-anchor="middle">%s</text>`+"\n",
+					x+slotWidth/2, y+slotHeight-tempBarHeight-8, html.EscapeString(temp.Text))
+			}
+		}
+		b.WriteString("</g>\n")
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func slotTitle(e enclosure, slot int, pd *diskutil.PhysicalDriveStat, t *threshold.Evaluator) string {
+	if pd == nil {
+		return fmt.Sprintf("%d:%d empty", e.ID, slot)
+	}
+	lines := []string{fmt.Sprintf("%s %s", pd.Slot(), pd.NormalizedState())}
+	for _, field := range [][2]string{
+		{"Model", strings.TrimSpace(pd.Brand + " " + pd.Model)},
+		{"Serial", pd.SerialNumber},
+		{"WWN", pd.WWN},
+		{"Size", pd.RawSize},
+		{"Temperature", driveTemperature(t, pd).Text},
+	} {
+		if field[1] != "" && field[1] != "-" {
+			lines = append(lines, field[0]+": "+field[1])
+		}
+	}
+	lines = append(lines, fmt.Sprintf("Media errors: %d, predictive failures: %d", pd.MediaErrorCount, pd.PredictiveFailureCount))
+	return strings.Join(lines, "\n")
+}
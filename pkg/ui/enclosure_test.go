@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// svg is the part of an enclosure image the tests look at
+type svg struct {
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+	Bays   []struct {
+		Title string `xml:"title"`
+		Rects []struct {
+			Fill string `xml:"fill,attr"`
+		} `xml:"rect"`
+	} `xml:"g"`
+}
+
+func TestEnclosure(t *testing.T) {
+	type bay struct {
+		title string
+		fill  string
+		// temperature is the colour of the temperature bar, "" when there is none
+		temperature string
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		width, height int
+		bays          []bay
+	}{
+		{
+			name:   "empty slot between drives",
+			path:   "0/32.svg",
+			width:  2*enclosurePad + 4*slotWidth + 3*slotGap,
+			height: 2*enclosurePad + headerHeight + slotHeight,
+			bays: []bay{
+				{title: "32:0 online", fill: slotColours["ok"], temperature: slotColours["ok"]},
+				{title: "32:1 empty", fill: slotColours[""]},
+				{title: "32:2 rebuild", fill: slotColours["warning"], temperature: slotColours["warning"]},
+				{title: "32:3 failed", fill: slotColours["critical"], temperature: slotColours["critical"]},
+			},
+		},
+		{
+			name:   "narrower than the header",
+			path:   "1/32.svg",
+			width:  minWidth,
+			height: 2*enclosurePad + headerHeight + slotHeight,
+			bays:   []bay{{title: "32:0 jbod", fill: slotColours["ok"]}},
+		},
+		{
+			name:   "second row",
+			path:   "0/33.svg",
+			width:  2*enclosurePad + slotsPerRow*slotWidth + (slotsPerRow-1)*slotGap,
+			height: 2*enclosurePad + headerHeight + 2*slotHeight + slotGap,
+			bays: func() []bay {
+				bays := make([]bay, 14)
+				for slot := range bays {
+					bays[slot] = bay{title: "33:" + strconv.Itoa(slot) + " empty", fill: slotColours[""]}
+				}
+				bays[13] = bay{title: "33:13 unconfigured_good", fill: slotColours["ok"]}
+				return bays
+			}(),
+		},
+	}
+
+	h := newHandler(t, testSnapshot(), time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.Enclosure(rec, httptest.NewRequest(http.MethodGet, EnclosurePrefix+tt.path, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("got content type %q", ct)
+			}
+			var image svg
+			if err := xml.Unmarshal(rec.Body.Bytes(), &image); err != nil {
+				t.Fatalf("invalid SVG: %v\n%s", err, rec.Body)
+			}
+			if image.Width != tt.width || image.Height != tt.height {
+				t.Errorf("got %dx%d, want %dx%d", image.Width, image.Height, tt.width, tt.height)
+			}
+			if len(image.Bays) != len(tt.bays) {
+				t.Fatalf("got %d bays, want %d", len(image.Bays), len(tt.bays))
+			}
+			for slot, want := range tt.bays {
+				got := image.Bays[slot]
+				if title := strings.SplitN(got.Title, "\n", 2)[0]; title != want.title {
+					t.Errorf("slot %d: got title %q, want %q", slot, title, want.title)
+				}
+				if got.Rects[0].Fill != want.fill {
+					t.Errorf("slot %d: got fill %s, want %s", slot, got.Rects[0].Fill, want.fill)
+				}
+				temperature := ""
+				if len(got.Rects) > 1 {
+					temperature = got.Rects[1].Fill
+				}
+				if temperature != want.temperature {
+					t.Errorf("slot %d: got temperature bar %q, want %q", slot, temperature, want.temperature)
+				}
+			}
+		})
+	}
+}
+
+func TestEnclosureErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		collect bool
+		path    string
+		code    int
+		message string
+	}{
+		{name: "no data yet", path: "0/32.svg", code: http.StatusServiceUnavailable, message: "no data collected yet"},
+		{name: "missing enclosure", collect: true, path: "0/64.svg", code: http.StatusNotFound, message: "no drives in enclosure 64 on controller 0"},
+		{name: "missing controller", collect: true, path: "2/32.svg", code: http.StatusNotFound, message: "no drives in enclosure 32 on controller 2"},
+		{name: "not an image", collect: true, path: "0/32", code: http.StatusNotFound, message: "expected /ui/enclosure/<controller>/<enclosure>.svg"},
+		{name: "too deep", collect: true, path: "0/32/0.svg", code: http.StatusNotFound, message: "expected /ui/enclosure/<controller>/<enclosure>.svg"},
+		{name: "invalid controller", collect: true, path: "c0/32.svg", code: http.StatusNotFound, message: "invalid controller: c0"},
+		{name: "invalid enclosure", collect: true, path: "0/e32.svg", code: http.StatusNotFound, message: "invalid enclosure: e32.svg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := testSnapshot()
+			if !tt.collect {
+				snapshot = nil
+			}
+			rec := httptest.NewRecorder()
+			newHandler(t, snapshot, time.Minute).Enclosure(rec, httptest.NewRequest(http.MethodGet, EnclosurePrefix+tt.path, nil))
+
+			if rec.Code != tt.code {
+				t.Errorf("got status %d, want %d", rec.Code, tt.code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.message {
+				t.Errorf("got %q, want %q", body, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package ui
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboard = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"severity":    func(s diskutil.Severity) string { return s.String() },
+	"pdSeverity":  func(p *diskutil.PhysicalDriveStat) string { return p.NormalizedState().Severity().String() },
+	"vdSeverity":  func(v *diskutil.VirtualDriveStat) string { return v.NormalizedState().Severity().String() },
+	"bbuSeverity": bbuSeverity,
+	"ctlSeverity": controllerSeverity,
+	"driveTemp":   driveTemperature,
+	"ctlTemp":     controllerTemperature,
+	"rocTemp":     rocTemperature,
+	"bbuTemp":     bbuTemperature,
+	"countClass":  countClass,
+	"ago":         ago,
+	"duration":    func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+}).Parse(dashboardHTML))
+
+// Handler renders the HTML dashboard from the background poller's last
+// snapshot. The page reloads itself once per collection interval.
+// Temperatures and counters are coloured by the thresholds.
+type Handler struct {
+	poller      *poller.Poller
+	thresholds  *threshold.Evaluator
+	version     string
+	metricsPath string
+}
+
+func NewHandler(p *poller.Poller, t *threshold.Evaluator, version, metricsPath string) *Handler {
+	return &Handler{poller: p, thresholds: t, version: version, metricsPath: metricsPath}
+}
+
+// dashboardData is what the template renders
+type dashboardData struct {
+	Version     string
+	MetricsPath string
+	Refresh     int
+	Snapshot    *diskutil.Snapshot
+	Thresholds  *threshold.Evaluator
+	Severity    diskutil.Severity
+	Problems    []diskutil.Problem
+	Rebuilds    []rebuild
+	Enclosures  []enclosure
+	Status      poller.Status
+}
+
+// rebuild is a background operation shown in the "Running operations" table
+type rebuild struct {
+	Component string
+	Operation string
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := dashboardData{
+		Version:     h.version,
+		MetricsPath: h.metricsPath,
+		Refresh:     int(h.poller.Interval().Seconds()),
+		Snapshot:    h.poller.Snapshot(),
+		Thresholds:  h.thresholds,
+		Status:      h.poller.Status(),
+	}
+	if data.Refresh < 5 {
+		data.Refresh = 5
+	}
+	if data.Snapshot != nil {
+		data.Severity = h.thresholds.Severity(data.Snapshot)
+		data.Problems = h.thresholds.Problems(data.Snapshot)
+		data.Rebuilds = rebuilds(data.Snapshot)
+		data.Enclosures = enclosures(data.Snapshot)
+	}
+
+	var out strings.Builder
+	if err := dashboard.Execute(&out, data); err != nil {
+		log.Printf("ERROR: Failed to render dashboard: %v", err)
+		http.Error(w, "failed to render dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(out.String()))
+}
+
+// rebuilds lists drives that are rebuilding or copying back and virtual
+// drives with a background operation in progress
+func rebuilds(s *diskutil.Snapshot) []rebuild {
+	var result []rebuild
+	for _, pd := range s.PhysicalDrives {
+		switch state := pd.NormalizedState(); state {
+		case diskutil.PDStateRebuild, diskutil.PDStateCopyback:
+			result = append(result, rebuild{
+				Component: fmt.Sprintf("pd %d/%s", pd.AdapterIndex, pd.Slot()),
+				Operation: string(state),
+			})
+		}
+	}
+	for _, vd := range s.VirtualDrives {
+		progress := strings.TrimSpace(vd.OngoingProgresses)
+		if progress == "" || strings.EqualFold(progress, "none") {
+			continue
+		}
+		result = append(result, rebuild{
+			Component: fmt.Sprintf("vd %d/%d", vd.AdapterIndex, vd.TargetId),
+			Operation: progress,
+		})
+	}
+	return result
+}
+
+func bbuSeverity(b *diskutil.BatteryBackupStat) string {
+	switch {
+	case strings.EqualFold(b.ReplacementRequired, "yes"):
+		return diskutil.SeverityCritical.String()
+	case b.BatteryState != "" && !strings.EqualFold(b.BatteryState, "optimal"):
+		return diskutil.SeverityWarning.String()
+	}
+	return diskutil.SeverityOK.String()
+}
+
+func controllerSeverity(c *diskutil.ControllerStat) string {
+	if c.ControllerStatus != "" && !strings.EqualFold(c.ControllerStatus, "optimal") {
+		return diskutil.SeverityCritical.String()
+	}
+	return diskutil.SeverityOK.String()
+}
+
+// reading is a temperature with the class used to colour it
+type reading struct {
+	Text  string
+	Class string
+}
+
+func temperature(celsius float64, severity diskutil.Severity) reading {
+	if celsius <= 0 {
+		return reading{Text: "-"}
+	}
+	return reading{Text: fmt.Sprintf("%.0f °C", celsius), Class: severity.String()}
+}
+
+func driveTemperature(t *threshold.Evaluator, p *diskutil.PhysicalDriveStat) reading {
+	celsius := diskutil.ParseTemperature(p.DriveTemperature)
+	component := fmt.Sprintf("pd %d/%s", p.AdapterIndex, p.Slot())
+	return temperature(celsius, t.Check(threshold.DriveTemperature, component, p.Model, celsius))
+}
+
+func controllerTemperature(t *threshold.Evaluator, c *diskutil.ControllerStat) reading {
+	celsius := float64(c.ControllerTemperature)
+	component := fmt.Sprintf("controller %d", c.AdapterIndex)
+	return temperature(celsius, t.Check(threshold.ControllerTemperature, component, c.ProductName, celsius))
+}
+
+func rocTemperature(t *threshold.Evaluator, c *diskutil.ControllerStat) reading {
+	celsius := float64(c.ROCTemperature)
+	component := fmt.Sprintf("controller %d", c.AdapterIndex)
+	return temperature(celsius, t.Check(threshold.ROCTemperature, component, c.ProductName, celsius))
+}
+
+func bbuTemperature(t *threshold.Evaluator, b *diskutil.BatteryBackupStat) reading {
+	celsius := float64(b.Temperature)
+	component := fmt.Sprintf("%s %d", b.Kind(), b.AdapterIndex)
+	return temperature(celsius, t.Check(threshold.BBUTemperature, component, b.BatteryType, celsius))
+}
+
+// countClass highlights an error counter of a drive by its thresholds, or
+// any non-zero counter when the counter has none
+func countClass(t *threshold.Evaluator, name string, p *diskutil.PhysicalDriveStat) string {
+	n := map[string]int{
+		threshold.MediaErrors:        p.MediaErrorCount,
+		threshold.OtherErrors:        p.OtherErrorCount,
+		threshold.PredictiveFailures: p.PredictiveFailureCount,
+	}[name]
+	component := fmt.Sprintf("pd %d/%s", p.AdapterIndex, p.Slot())
+	if limit := t.Limit(name, component, p.Model); limit.Warning > 0 || limit.Critical > 0 {
+		if severity := t.Check(name, component, p.Model, float64(n)); severity != diskutil.SeverityOK {
+			return severity.String()
+		}
+		return ""
+	}
+	if n > 0 {
+		return diskutil.SeverityWarning.String()
+	}
+	return ""
+}
+
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return time.Since(t).Truncate(time.Second).String() + " ago"
+}
@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/megaraid-exporter/pkg/backend"
+	"github.com/yourusername/megaraid-exporter/pkg/diskutil"
+	"github.com/yourusername/megaraid-exporter/pkg/poller"
+	"github.com/yourusername/megaraid-exporter/pkg/threshold"
+)
+
+// fakeBackend returns the same snapshot on every collection
+type fakeBackend struct {
+	snapshot *diskutil.Snapshot
+}
+
+func (f fakeBackend) Name() string                                 { return "storcli" }
+func (f fakeBackend) Path() string                                 { return "/bin/true" }
+func (f fakeBackend) Version() (string, error)                     { return "storcli 007.1017", nil }
+func (f fakeBackend) Collect(sections []string) *diskutil.Snapshot { return f.snapshot }
+
+// newHandler returns a dashboard for snapshot, or one that has not collected
+// anything yet when snapshot is nil
+func newHandler(t *testing.T, snapshot *diskutil.Snapshot, interval time.Duration) *Handler {
+	t.Helper()
+	p, err := poller.New(fakeBackend{snapshot: snapshot}, backend.Sections, interval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != nil {
+		p.Collect()
+	}
+	thresholds, err := threshold.New(threshold.Config{Limits: map[string]threshold.Limit{
+		threshold.DriveTemperature: {Warning: 50, Critical: 60},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(p, thresholds, "1.2.3", "/metrics")
+}
+
+func testSnapshot() *diskutil.Snapshot {
+	return &diskutil.Snapshot{
+		CollectedAt: time.Now(),
+		Backend:     "storcli",
+		Controllers: []*diskutil.ControllerStat{{AdapterIndex: 0, ProductName: "PERC H730 Mini", ControllerStatus: "Optimal"}},
+		VirtualDrives: []*diskutil.VirtualDriveStat{
+			{AdapterIndex: 0, TargetId: 0, State: "Dgrd", OngoingProgresses: "Rebuild 42%"},
+		},
+		PhysicalDrives: []*diskutil.PhysicalDriveStat{
+			{AdapterIndex: 0, EnclosureDeviceId: 32, SlotNumber: 0, SerialNumber: "S0K1AAAA", FirmwareState: "Onln", DriveTemperature: "36C"},
+			{AdapterIndex: 0, EnclosureDeviceId: 32, SlotNumber: 2, SerialNumber: "S0K1BBBB", FirmwareState: "Rbld", DriveTemperature: "55C"},
+			{AdapterIndex: 0, EnclosureDeviceId: 32, SlotNumber: 3, SerialNumber: "S0K1CCCC", FirmwareState: "F", DriveTemperature: "62C"},
+			{AdapterIndex: 0, EnclosureDeviceId: 33, SlotNumber: 13, SerialNumber: "S0K1DDDD", FirmwareState: "UGood"},
+			{AdapterIndex: 1, EnclosureDeviceId: 32, SlotNumber: 0, SerialNumber: "S0K1EEEE", FirmwareState: "JBOD"},
+		},
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot *diskutil.Snapshot
+		interval time.Duration
+		path     string
+		code     int
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "no data yet",
+			interval: time.Second,
+			path:     "/",
+			code:     http.StatusOK,
+			contains: []string{`content="5"`, "no data collected yet", `href="/metrics"`, "Version 1.2.3"},
+			excludes: []string{"No controllers"},
+		},
+		{
+			name:     "snapshot",
+			snapshot: testSnapshot(),
+			interval: 30 * time.Second,
+			path:     "/",
+			code:     http.StatusOK,
+			contains: []string{
+				`content="30"`,
+				"PERC H730 Mini",
+				"S0K1AAAA",
+				`<td class="critical">62 °C</td>`,
+				`<td class="warning">55 °C</td>`,
+				"Rebuild 42%",
+				`href="/ui/enclosure/0/32.svg"`,
+				`href="/ui/enclosure/0/33.svg"`,
+				`href="/ui/enclosure/1/32.svg"`,
+				"No BBU or CacheVault",
+			},
+			excludes: []string{"no data collected yet"},
+		},
+		{
+			name:     "empty snapshot",
+			snapshot: &diskutil.Snapshot{CollectedAt: time.Now(), Backend: "storcli"},
+			interval: time.Minute,
+			path:     "/",
+			code:     http.StatusOK,
+			contains: []string{"No controllers", "No virtual drives", "No physical drives"},
+			excludes: []string{"Enclosures:"},
+		},
+		{
+			name:     "unknown path",
+			interval: time.Minute,
+			path:     "/dashboard",
+			code:     http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newHandler(t, tt.snapshot, tt.interval).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.code {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.code)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+				t.Errorf("got content type %q", ct)
+			}
+			body := rec.Body.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("dashboard does not contain %q", s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(body, s) {
+					t.Errorf("dashboard contains %q", s)
+				}
+			}
+		})
+	}
+}